@@ -3,18 +3,32 @@ package config
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/sethvargo/go-envconfig"
 )
 
 type Config struct {
-	Debug               bool   `env:"DEBUG, default=false"`
-	SpotifyClientId     string `env:"SPOTIFY_CLIENT_ID, required"`
-	SpotifyClientSecret string `env:"SPOTIFY_CLIENT_SECRET, required"`
-	SpotifyRedirectUri  string `env:"SPOTIFY_CLIENT_REDIRECT_URI, default=http://localhost:28542/callback"`
-	TidalClientId       string `env:"TIDAL_CLIENT_ID, required"`
-	TidalClientSecret   string `env:"TIDAL_CLIENT_SECRET, required"`
-	DataPath            string `env:"DATA_PATH, default=/data"`
+	Debug                     bool          `env:"DEBUG, default=false"`
+	SpotifyClientId           string        `env:"SPOTIFY_CLIENT_ID, required"`
+	SpotifyClientSecret       string        `env:"SPOTIFY_CLIENT_SECRET"` // optional, PKCE is used without it
+	SpotifyRedirectUri        string        `env:"SPOTIFY_CLIENT_REDIRECT_URI, default=http://localhost:28542/callback"`
+	TidalClientId             string        `env:"TIDAL_CLIENT_ID, required"`
+	TidalClientSecret         string        `env:"TIDAL_CLIENT_SECRET, required"`
+	DataPath                  string        `env:"DATA_PATH, default=/data"`
+	MissingTrackRetryInterval time.Duration `env:"MISSING_TRACK_RETRY_INTERVAL, default=168h"`
+	MatchThreshold            float64       `env:"MATCH_THRESHOLD, default=0.7"`
+	MatchConcurrency          int           `env:"MATCH_CONCURRENCY, default=4"`
+	TidalCountryCode          string        `env:"TIDAL_COUNTRY_CODE, default=US"`
+	ConfigPassphrase          string        `env:"CONFIG_PASSPHRASE"` // encrypts config.json, see ConfigKey
+	ConfigKeyFile             string        `env:"CONFIG_KEY_FILE"`
+	// Profile is the name of the profile set by UseProfile, empty if no profile is used
+	Profile string
+}
+
+// ConfigKey returns the key config.json is encrypted with.
+func (c *Config) ConfigKey() ConfigKey {
+	return ConfigKey{Passphrase: c.ConfigPassphrase, KeyFile: c.ConfigKeyFile}
 }
 
 func Init() (*Config, error) {
@@ -25,5 +39,13 @@ func Init() (*Config, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
+	if !countryCodeRegex.MatchString(c.TidalCountryCode) {
+		return nil, fmt.Errorf("failed to load config: TIDAL_COUNTRY_CODE must be a two letter country code such as \"US\"")
+	}
+
+	if c.ConfigPassphrase != "" && c.ConfigKeyFile != "" {
+		return nil, fmt.Errorf("failed to load config: set only one of CONFIG_PASSPHRASE and CONFIG_KEY_FILE")
+	}
+
 	return &c, nil
 }
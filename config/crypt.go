@@ -0,0 +1,161 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	configEncryption = "aes-256-gcm"
+	// kdfPBKDF2 derives the key from a passphrase, kdfNone uses the key of a key file as is
+	kdfPBKDF2 = "pbkdf2-sha256"
+	kdfNone   = "none"
+	// OWASP recommended iterations for PBKDF2-HMAC-SHA256
+	pbkdf2Iterations = 600000
+	keySize          = 32
+	saltSize         = 16
+)
+
+// ConfigKey is the secret the JSON config is encrypted with.
+// Set either the passphrase or the path of a key file, if neither is set the config is not encrypted.
+type ConfigKey struct {
+	Passphrase string
+	// KeyFile contains a base64 encoded 32 byte key, e.g. generated with `openssl rand -base64 32`
+	KeyFile string
+}
+
+func (k ConfigKey) enabled() bool {
+	return k.Passphrase != "" || k.KeyFile != ""
+}
+
+// encryptedConfig is the envelope of an encrypted config file.
+type encryptedConfig struct {
+	Encryption string `json:"encryption"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations,omitempty"`
+	Salt       []byte `json:"salt,omitempty"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// configCipher seals the config with a key derived once, new nonces are generated for every save.
+type configCipher struct {
+	kdf        string
+	iterations int
+	salt       []byte
+	aead       cipher.AEAD
+}
+
+// newConfigCipher returns the cipher of the key, deriving passphrase keys with a new salt.
+func newConfigCipher(key ConfigKey) (*configCipher, error) {
+	if key.Passphrase == "" {
+		return keyFileCipher(key.KeyFile)
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return passphraseCipher(key.Passphrase, salt, pbkdf2Iterations)
+}
+
+func passphraseCipher(passphrase string, salt []byte, iterations int) (*configCipher, error) {
+	aead, err := newAEAD(pbkdf2.Key([]byte(passphrase), salt, iterations, keySize, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	return &configCipher{kdf: kdfPBKDF2, iterations: iterations, salt: salt, aead: aead}, nil
+}
+
+func keyFileCipher(path string) (*configCipher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config key file: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("config key file must contain a base64 encoded %d byte key", keySize)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &configCipher{kdf: kdfNone, aead: aead}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the config JSON and returns the envelope.
+func (c *configCipher) seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(encryptedConfig{
+		Encryption: configEncryption,
+		KDF:        c.kdf,
+		Iterations: c.iterations,
+		Salt:       c.salt,
+		Nonce:      nonce,
+		Ciphertext: c.aead.Seal(nil, nonce, plaintext, nil),
+	}, "", "	")
+}
+
+// parseEncryptedConfig returns the envelope if the file is encrypted.
+func parseEncryptedConfig(data []byte) (*encryptedConfig, bool) {
+	var envelope encryptedConfig
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Encryption == "" {
+		return nil, false
+	}
+	return &envelope, true
+}
+
+// openEncryptedConfig decrypts the envelope with the key.
+// The returned cipher reuses the salt of the envelope so the passphrase is only derived once.
+func openEncryptedConfig(envelope *encryptedConfig, key ConfigKey) ([]byte, *configCipher, error) {
+	if envelope.Encryption != configEncryption {
+		return nil, nil, fmt.Errorf("unsupported config encryption %q", envelope.Encryption)
+	}
+
+	var c *configCipher
+	var err error
+	switch {
+	case envelope.KDF == kdfPBKDF2 && key.Passphrase != "":
+		c, err = passphraseCipher(key.Passphrase, envelope.Salt, envelope.Iterations)
+	case envelope.KDF == kdfNone && key.KeyFile != "":
+		c, err = keyFileCipher(key.KeyFile)
+	case envelope.KDF == kdfPBKDF2:
+		return nil, nil, fmt.Errorf("config is encrypted with a passphrase, set CONFIG_PASSPHRASE")
+	case envelope.KDF == kdfNone:
+		return nil, nil, fmt.Errorf("config is encrypted with a key file, set CONFIG_KEY_FILE")
+	default:
+		return nil, nil, fmt.Errorf("unsupported config key derivation %q", envelope.KDF)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plaintext, err := c.aead.Open(nil, envelope.Nonce, envelope.Ciphertext, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt config, the passphrase or key is wrong")
+	}
+
+	return plaintext, c, nil
+}
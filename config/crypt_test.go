@@ -0,0 +1,133 @@
+package config
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testIterations keeps key derivation fast, the iterations are read from the envelope when opening
+const testIterations = 1000
+
+func writeTestKeyFile(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "config.key")
+	if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSealOpen(t *testing.T) {
+	keyFile := writeTestKeyFile(t)
+
+	tests := []struct {
+		name   string
+		key    ConfigKey
+		cipher func() (*configCipher, error)
+	}{
+		{
+			name: "passphrase",
+			key:  ConfigKey{Passphrase: "correct horse battery staple"},
+			cipher: func() (*configCipher, error) {
+				return passphraseCipher("correct horse battery staple", []byte("0123456789abcdef"), testIterations)
+			},
+		},
+		{
+			name: "key file",
+			key:  ConfigKey{KeyFile: keyFile},
+			cipher: func() (*configCipher, error) {
+				return keyFileCipher(keyFile)
+			},
+		},
+	}
+
+	plaintext := []byte(`{"spotify":{"access_token":"secret"}}`)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := tt.cipher()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sealed, err := c.seal(plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(sealed, []byte("secret")) {
+				t.Fatal("sealed config contains the plaintext")
+			}
+
+			envelope, ok := parseEncryptedConfig(sealed)
+			if !ok {
+				t.Fatal("sealed config is not recognized as encrypted")
+			}
+
+			opened, _, err := openEncryptedConfig(envelope, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(opened, plaintext) {
+				t.Errorf("opened config = %s, want %s", opened, plaintext)
+			}
+
+			// every save uses a new nonce
+			resealed, err := c.seal(plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(sealed, resealed) {
+				t.Error("sealing twice returned the same envelope")
+			}
+		})
+	}
+}
+
+func TestOpenWrongKey(t *testing.T) {
+	c, err := passphraseCipher("passphrase", []byte("0123456789abcdef"), testIterations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := c.seal([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, ok := parseEncryptedConfig(sealed)
+	if !ok {
+		t.Fatal("sealed config is not recognized as encrypted")
+	}
+
+	tests := []struct {
+		name string
+		key  ConfigKey
+		want string
+	}{
+		{"wrong passphrase", ConfigKey{Passphrase: "wrong"}, "passphrase or key is wrong"},
+		{"no key", ConfigKey{}, "CONFIG_PASSPHRASE"},
+		{"key file instead of passphrase", ConfigKey{KeyFile: writeTestKeyFile(t)}, "CONFIG_PASSPHRASE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := openEncryptedConfig(envelope, tt.key)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("openEncryptedConfig() error = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEncryptedConfigPlaintext(t *testing.T) {
+	if _, ok := parseEncryptedConfig([]byte(`{"spotify":{},"tidal":{}}`)); ok {
+		t.Error("plaintext config is recognized as encrypted")
+	}
+}
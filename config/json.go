@@ -4,10 +4,16 @@ import (
 	"encoding/json"
 	"io"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/rs/zerolog/log"
 )
 
+// configFileMode restricts the config to the owner as it contains the tokens of every service
+const configFileMode = 0600
+
 type JsonConfig struct {
 	Spotify SpotifyConfig `json:"spotify"`
 	Tidal   TidalConfig   `json:"tidal"`
@@ -18,26 +24,35 @@ type SpotifyConfig struct {
 	RefreshToken string    `json:"refresh_token"`
 	Expiry       time.Time `json:"expiry"`
 	TokenType    string    `json:"token_type"`
+	Scope        string    `json:"scope"`
 }
 
 type TidalConfig struct {
-	UserID       string `json:"user_id"`
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	UserID       string    `json:"user_id"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+	Scope        string    `json:"scope"`
 }
 
 type JsonConfigService struct {
 	mu         sync.Mutex
 	JsonConfig JsonConfig
 	Path       string
+	key        ConfigKey
+	cipher     *configCipher // nil if the config is not encrypted
 }
 
-func NewJsonConfigService(path string) *JsonConfigService {
+// NewJsonConfigService returns the config stored at the path, encrypted with the key if it is set.
+func NewJsonConfigService(path string, key ConfigKey) *JsonConfigService {
 	return &JsonConfigService{
 		Path: path,
+		key:  key,
 	}
 }
 
+// Init loads the config, creating it if it doesn't exist.
+// Plaintext configs are encrypted in place if a key is set, and configs readable by other users are restricted to the owner.
 func (s *JsonConfigService) Init() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -46,6 +61,9 @@ func (s *JsonConfigService) Init() error {
 	if err != nil {
 		// Create a new config file if it doesn't exist
 		if os.IsNotExist(err) {
+			if err := s.initCipher(); err != nil {
+				return err
+			}
 			s.JsonConfig = JsonConfig{}
 			return s.Save()
 		}
@@ -59,6 +77,24 @@ func (s *JsonConfigService) Init() error {
 		return err
 	}
 
+	// migrate plaintext configs and permissions of earlier versions
+	migrate := false
+
+	if envelope, ok := parseEncryptedConfig(data); ok {
+		plaintext, c, err := openEncryptedConfig(envelope, s.key)
+		if err != nil {
+			return err
+		}
+		data = plaintext
+		s.cipher = c
+	} else if s.key.enabled() {
+		if err := s.initCipher(); err != nil {
+			return err
+		}
+		log.Info().Str("path", s.Path).Msg("encrypting config")
+		migrate = true
+	}
+
 	var config JsonConfig
 	if err := json.Unmarshal(data, &config); err != nil {
 		return err
@@ -66,20 +102,79 @@ func (s *JsonConfigService) Init() error {
 
 	s.JsonConfig = config
 
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm() != configFileMode {
+		migrate = true
+	}
+
+	if migrate {
+		return s.Save()
+	}
+
+	return nil
+}
+
+// initCipher creates the cipher of a new or plaintext config if a key is set.
+func (s *JsonConfigService) initCipher() error {
+	if !s.key.enabled() {
+		return nil
+	}
+
+	c, err := newConfigCipher(s.key)
+	if err != nil {
+		return err
+	}
+	s.cipher = c
+
 	return nil
 }
 
+// Save writes the config to a temporary file and renames it over the config so the config is never partially written.
 func (s *JsonConfigService) Save() error {
 	data, err := json.MarshalIndent(s.JsonConfig, "", "	")
 	if err != nil {
 		return err
 	}
 
-	if err := os.WriteFile(s.Path, data, 0644); err != nil {
+	if s.cipher != nil {
+		data, err = s.cipher.seal(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeFileAtomic(s.Path, data)
+}
+
+// writeFileAtomic replaces the file with the data, readable only by the owner.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
 		return err
 	}
+	// remove the temporary file if it was not renamed
+	defer os.Remove(tmp.Name())
 
-	return nil
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(configFileMode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
 }
 
 func (s *JsonConfigService) Get() JsonConfig {
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInitEncryptsPlaintextConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	plaintext := []byte(`{"spotify":{"access_token":"spotify-token"},"tidal":{"refresh_token":"tidal-token"}}`)
+	if err := os.WriteFile(path, plaintext, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	key := ConfigKey{Passphrase: "passphrase"}
+	s := NewJsonConfigService(path, key)
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Get().Spotify.AccessToken; got != "spotify-token" {
+		t.Errorf("spotify access token = %q, want %q", got, "spotify-token")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("spotify-token")) || bytes.Contains(data, []byte("tidal-token")) {
+		t.Error("config still contains plaintext tokens")
+	}
+	if _, ok := parseEncryptedConfig(data); !ok {
+		t.Error("config was not encrypted")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != configFileMode {
+		t.Errorf("config mode = %v, want %v", info.Mode().Perm(), os.FileMode(configFileMode))
+	}
+
+	// the encrypted config is read back with the same key
+	reopened := NewJsonConfigService(path, key)
+	if err := reopened.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.Get().Tidal.RefreshToken; got != "tidal-token" {
+		t.Errorf("tidal refresh token = %q, want %q", got, "tidal-token")
+	}
+
+	// and cannot be read without it
+	if err := NewJsonConfigService(path, ConfigKey{}).Init(); err == nil {
+		t.Error("encrypted config was read without a key")
+	}
+}
+
+func TestInitRestrictsPlaintextConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"spotify":{"access_token":"spotify-token"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewJsonConfigService(path, ConfigKey{})
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := parseEncryptedConfig(data); ok {
+		t.Error("config was encrypted without a key")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != configFileMode {
+		t.Errorf("config mode = %v, want %v", info.Mode().Perm(), os.FileMode(configFileMode))
+	}
+}
+
+func TestInitCreatesEncryptedConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	s := NewJsonConfigService(path, ConfigKey{KeyFile: writeTestKeyFile(t)})
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := s.Get()
+	c.Spotify.RefreshToken = "spotify-token"
+	if err := s.Update(c); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("spotify-token")) {
+		t.Error("updated config contains the plaintext token")
+	}
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("config directory has %d files, want 1", len(entries))
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// profile names are used as directory names
+var profileNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+func (c *Config) profilesPath() string {
+	return filepath.Join(c.DataPath, "profiles")
+}
+
+// UseProfile moves the data path to the directory of the profile, DATA_PATH/profiles/<name>.
+// Each profile has its own tokens, database, and output files. The directory is created if it doesn't exist.
+func (c *Config) UseProfile(name string) error {
+	if !profileNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q, use letters, numbers, - and _", name)
+	}
+
+	c.DataPath = filepath.Join(c.profilesPath(), name)
+	c.Profile = name
+
+	return os.MkdirAll(c.DataPath, 0700)
+}
+
+// Profiles returns the names of the profiles in the data path, sorted by name.
+func (c *Config) Profiles() ([]string, error) {
+	entries, err := os.ReadDir(c.profilesPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var profiles []string
+	for _, entry := range entries {
+		if entry.IsDir() && profileNameRegex.MatchString(entry.Name()) {
+			profiles = append(profiles, entry.Name())
+		}
+	}
+	sort.Strings(profiles)
+
+	return profiles, nil
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// services that jobs can sync from and to
+const (
+	ServiceSpotify = "spotify"
+	ServiceTidal   = "tidal"
+)
+
+// country codes are ISO 3166-1 alpha-2 codes
+var countryCodeRegex = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// SyncFile declares sync jobs, see sync.example.yaml.
+type SyncFile struct {
+	// Match is the default match settings of every job
+	Match MatchSettings `yaml:"match"`
+	Jobs  []SyncJob     `yaml:"jobs"`
+}
+
+// SyncJob syncs the selected playlists of the source to the destination.
+type SyncJob struct {
+	Name        string           `yaml:"name"`
+	Source      string           `yaml:"source"`
+	Destination string           `yaml:"destination"`
+	Playlists   PlaylistSelector `yaml:"playlists"`
+	Options     SyncJobOptions   `yaml:"options"`
+	// Match overrides the default match settings of the file
+	Match MatchSettings `yaml:"match"`
+}
+
+// PlaylistSelector selects playlists by ID, name, and owner.
+// A playlist is selected if it has one of the IDs or matches one of the names, or every playlist if neither are set.
+// If the owner is set only playlists of the owner are selected. Excluded playlists are never selected.
+type PlaylistSelector struct {
+	IDs []string `yaml:"ids"`
+	// Names are glob patterns, e.g. "Daily Mix *", matched case-insensitively
+	Names   []string        `yaml:"names"`
+	Owner   string          `yaml:"owner"`
+	Exclude PlaylistExclude `yaml:"exclude"`
+}
+
+// PlaylistExclude lists the playlists a selector never selects.
+type PlaylistExclude struct {
+	IDs   []string `yaml:"ids"`
+	Names []string `yaml:"names"`
+}
+
+// SyncJobOptions are the options of a job, each has the same effect as the flag of the same name.
+type SyncJobOptions struct {
+	Mirror                bool   `yaml:"mirror"`
+	PreserveOrder         bool   `yaml:"preserve_order"`
+	Full                  bool   `yaml:"full"`
+	Bidirectional         bool   `yaml:"bidirectional"`
+	SaveMissingTracks     bool   `yaml:"save_missing_tracks"`
+	SaveTidalPlaylist     bool   `yaml:"save_tidal_playlist"`
+	SaveNavidromePlaylist bool   `yaml:"save_navidrome_playlist"`
+	LikedSongs            string `yaml:"liked_songs"`
+	SavedAlbums           bool   `yaml:"saved_albums"`
+	FollowedArtists       bool   `yaml:"followed_artists"`
+}
+
+// MatchSettings tune the track matcher. Unset settings fall back to the file defaults, then to the env vars.
+type MatchSettings struct {
+	Threshold   *float64 `yaml:"threshold"`
+	CountryCode string   `yaml:"country_code"`
+}
+
+// LoadSyncFile reads and validates the sync file. Every validation error is reported at once.
+func LoadSyncFile(filePath string) (*SyncFile, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var syncFile SyncFile
+	decoder := yaml.NewDecoder(file)
+	// misspelled options are errors instead of being silently ignored
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&syncFile); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+
+	if err := syncFile.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s:\n%w", filePath, err)
+	}
+
+	return &syncFile, nil
+}
+
+func (f *SyncFile) validate() error {
+	var errs []error
+
+	errs = append(errs, f.Match.validate("match")...)
+
+	if len(f.Jobs) == 0 {
+		errs = append(errs, fmt.Errorf("no jobs declared"))
+	}
+
+	names := make(map[string]bool)
+	for i, job := range f.Jobs {
+		field := fmt.Sprintf("jobs[%d]", i)
+		if job.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", field))
+		} else if names[job.Name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate job name %q", field, job.Name))
+		} else {
+			field = fmt.Sprintf("job %q", job.Name)
+		}
+		names[job.Name] = true
+
+		// only Spotify to Tidal jobs are supported for now, Tidal to Spotify is synced with the spotify command
+		// and both directions with the bidirectional option
+		if job.Source != ServiceSpotify {
+			errs = append(errs, fmt.Errorf("%s: source must be %q, sync tidal to spotify with the spotify command or set options.bidirectional", field, ServiceSpotify))
+		}
+		if job.Destination != ServiceTidal {
+			errs = append(errs, fmt.Errorf("%s: destination must be %q, sync tidal to spotify with the spotify command or set options.bidirectional", field, ServiceTidal))
+		}
+
+		patterns := make([]string, 0, len(job.Playlists.Names)+len(job.Playlists.Exclude.Names))
+		patterns = append(patterns, job.Playlists.Names...)
+		patterns = append(patterns, job.Playlists.Exclude.Names...)
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid playlist name pattern %q", field, pattern))
+			}
+		}
+
+		// the values of the --liked-songs flag
+		if liked := job.Options.LikedSongs; liked != "" && liked != "favorites" && liked != "playlist" {
+			errs = append(errs, fmt.Errorf("%s: liked_songs must be \"favorites\" or \"playlist\"", field))
+		}
+
+		errs = append(errs, job.Match.validate(field+": match")...)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m MatchSettings) validate(field string) []error {
+	var errs []error
+	if m.Threshold != nil && (*m.Threshold < 0 || *m.Threshold > 1) {
+		errs = append(errs, fmt.Errorf("%s: threshold must be between 0 and 1", field))
+	}
+	if m.CountryCode != "" && !countryCodeRegex.MatchString(m.CountryCode) {
+		errs = append(errs, fmt.Errorf("%s: country_code must be a two letter country code such as \"US\"", field))
+	}
+	return errs
+}
+
+// MatchSettings returns the match threshold and country code of the job.
+// Env vars that are set take precedence over the job, which takes precedence over the defaults of the file.
+func (f *SyncFile) MatchSettings(job SyncJob, c *Config) (float64, string) {
+	threshold := c.MatchThreshold
+	if _, ok := os.LookupEnv("MATCH_THRESHOLD"); !ok {
+		if job.Match.Threshold != nil {
+			threshold = *job.Match.Threshold
+		} else if f.Match.Threshold != nil {
+			threshold = *f.Match.Threshold
+		}
+	}
+
+	countryCode := c.TidalCountryCode
+	if _, ok := os.LookupEnv("TIDAL_COUNTRY_CODE"); !ok {
+		if job.Match.CountryCode != "" {
+			countryCode = job.Match.CountryCode
+		} else if f.Match.CountryCode != "" {
+			countryCode = f.Match.CountryCode
+		}
+	}
+
+	return threshold, countryCode
+}
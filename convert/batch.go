@@ -0,0 +1,48 @@
+package convert
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/zibbp/spotify-playlist-sync/db"
+	libSpotify "github.com/zmb3/spotify/v2"
+
+	"github.com/rs/zerolog/log"
+)
+
+// pendingTrack is a matched track waiting to be added to the Tidal playlist.
+type pendingTrack struct {
+	SpotifyTrack *libSpotify.FullTrack
+	Match        *TrackMatch
+}
+
+// addTracksToTidalPlaylist adds the pending tracks to the Tidal playlist and records them in the database.
+func (s *Service) addTracksToTidalPlaylist(ctx context.Context, dbPlaylist string, tidalPlaylistID string, pendingTracks []pendingTrack) error {
+	if len(pendingTracks) == 0 {
+		return nil
+	}
+
+	tidalTrackIDs := make([]string, 0, len(pendingTracks))
+	for _, pending := range pendingTracks {
+		tidalTrackIDs = append(tidalTrackIDs, pending.Match.TidalID)
+	}
+
+	log.Info().Str("tidal_playlist_id", tidalPlaylistID).Msgf("adding %d tracks to tidal playlist", len(tidalTrackIDs))
+	err := s.TidalService.AddTracksToPlaylist(ctx, tidalPlaylistID, tidalTrackIDs)
+	if err != nil {
+		return err
+	}
+
+	// add tracks to database
+	for _, pending := range pendingTracks {
+		err := s.Queries.AddTrackToPlaylist(ctx, db.AddTrackToPlaylistParams{
+			PlaylistID: sql.NullString{String: dbPlaylist, Valid: true},
+			TrackID:    sql.NullString{String: pending.SpotifyTrack.ID.String(), Valid: true},
+		})
+		if err != nil {
+			log.Error().Err(err).Str("spotify_track_id", pending.SpotifyTrack.ID.String()).Str("spotify_track_name", pending.SpotifyTrack.Name).Msgf("error adding track to database")
+		}
+	}
+
+	return nil
+}
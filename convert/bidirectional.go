@@ -0,0 +1,298 @@
+package convert
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/zibbp/spotify-playlist-sync/db"
+	"github.com/zibbp/spotify-playlist-sync/spotify"
+	"github.com/zibbp/spotify-playlist-sync/tidal"
+	libSpotify "github.com/zmb3/spotify/v2"
+
+	"github.com/rs/zerolog/log"
+)
+
+// reconcileResult is the tracks of a reconciliation that need the attention of the user.
+type reconcileResult struct {
+	// MissingTracks were added on spotify but not found on tidal, with the reason keyed by Spotify track ID
+	MissingTracks       []*libSpotify.FullTrack
+	MissingTrackReasons map[string]string
+	// Conflicts are tracks added on one side and removed on the other, and tracks added on tidal that were not found on spotify
+	Conflicts []spotify.TrackConflict
+}
+
+// reconcilePlaylist performs a three-way merge of a linked Spotify and Tidal playlist.
+// The base is the set of tracks that were on both sides after the last reconciliation. Tracks added on either side since then are added
+// to the other, and tracks removed on either side are removed from the other. If a track was added on one side and removed on the other
+// the addition wins and a conflict is reported. Tracks that cannot be matched on the other service are reported as missing or as conflicts.
+// Without a base, e.g. the first reconciliation, the playlists are merged without removing any tracks.
+// During a dry run the changes are added to the playlist plan instead of being made.
+func (s *Service) reconcilePlaylist(ctx context.Context, dbPlaylist string, spotifyPlaylist libSpotify.SimplePlaylist, tidalPlaylistID string, opts SyncOptions, playlistPlan *PlaylistPlan) (*reconcileResult, error) {
+	log.Info().Str("spotify_playlist_id", spotifyPlaylist.ID.String()).Str("tidal_playlist_id", tidalPlaylistID).Msgf("reconciling playlist %s", spotifyPlaylist.Name)
+
+	// get all tracks from Spotify playlist
+	spotifyTracks, err := s.SpotifyService.GetPlaylistTracks(ctx, spotifyPlaylist.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	spotifyTrackMap := make(map[string]*libSpotify.FullTrack)
+	var spotifyTrackIDs []string
+	for _, spotifyTrack := range spotifyTracks {
+		if spotifyTrack == nil {
+			continue
+		}
+		if _, ok := spotifyTrackMap[spotifyTrack.ID.String()]; ok {
+			continue
+		}
+		spotifyTrackMap[spotifyTrack.ID.String()] = spotifyTrack
+		spotifyTrackIDs = append(spotifyTrackIDs, spotifyTrack.ID.String())
+	}
+
+	// get all tracks from Tidal playlist
+	// playlists that would be created during a dry run do not exist yet
+	tidalTrackMap := make(map[string]tidal.Track)
+	var tidalTrackIDs []string
+	var tidalTracks []tidal.Track
+	if tidalPlaylistID != "" {
+		tidalPlaylistTracks, err := s.TidalService.GetPlaylistTracks(ctx, tidalPlaylistID)
+		if err != nil {
+			return nil, err
+		}
+		tidalTracks = tidalPlaylistTracks.Items
+
+		log.Info().Str("platform", "tidal").Msgf("fetched %d tracks from playlist %s", len(tidalTracks), tidalPlaylistID)
+	}
+
+	for _, tidalTrack := range tidalTracks {
+		tidalID := strconv.FormatInt(tidalTrack.ID, 10)
+		if _, ok := tidalTrackMap[tidalID]; ok {
+			continue
+		}
+		tidalTrackMap[tidalID] = tidalTrack
+		tidalTrackIDs = append(tidalTrackIDs, tidalID)
+	}
+
+	baseTracks, err := s.Queries.GetPlaylistBaseTracks(ctx, dbPlaylist)
+	if err != nil {
+		return nil, err
+	}
+
+	baseBySpotify := make(map[string]string)
+	baseByTidal := make(map[string]string)
+	for _, baseTrack := range baseTracks {
+		baseBySpotify[baseTrack.SpotifyID] = baseTrack.TidalID
+		baseByTidal[baseTrack.TidalID] = baseTrack.SpotifyID
+	}
+
+	// pairs is the merged playlist that becomes the new base, spotify id to tidal id
+	pairs := make(map[string]string)
+	removeFromTidal := make(map[string]bool)
+	removeFromSpotify := make(map[string]bool)
+	var addToTidal []pendingTrack
+	var addToSpotify []libSpotify.ID
+	result := &reconcileResult{MissingTrackReasons: make(map[string]string)}
+
+	// tracks in the base that are missing from one side were removed from it
+	for _, baseTrack := range baseTracks {
+		_, inSpotify := spotifyTrackMap[baseTrack.SpotifyID]
+		_, inTidal := tidalTrackMap[baseTrack.TidalID]
+		switch {
+		case inSpotify && inTidal:
+			pairs[baseTrack.SpotifyID] = baseTrack.TidalID
+		case inTidal:
+			removeFromTidal[baseTrack.TidalID] = true
+		case inSpotify:
+			removeFromSpotify[baseTrack.SpotifyID] = true
+		}
+	}
+
+	// tracks added to spotify since the base
+	addedToTidal := make(map[string]bool)
+	for _, spotifyID := range spotifyTrackIDs {
+		if _, ok := baseBySpotify[spotifyID]; ok {
+			continue
+		}
+		spotifyTrack := spotifyTrackMap[spotifyID]
+
+		match, reason, err := s.spotifyToTidalTrack(ctx, spotifyTrack, opts)
+		if errors.Is(err, errTrackIgnored) {
+			continue
+		}
+		if err != nil {
+			reason = err.Error()
+		}
+		if match == nil {
+			log.Warn().Str("spotify_playlist_id", spotifyPlaylist.ID.String()).Str("spotify_track_id", spotifyID).Str("track_name", spotifyTrack.Name).Str("reason", reason).Msg("added on spotify but not found on tidal")
+			result.MissingTracks = append(result.MissingTracks, spotifyTrack)
+			result.MissingTrackReasons[spotifyID] = reason
+			continue
+		}
+
+		// a track replaced by a different release of the same recording keeps its tidal track
+		pairs[spotifyID] = match.TidalID
+		if _, inTidal := tidalTrackMap[match.TidalID]; inTidal || addedToTidal[match.TidalID] {
+			continue
+		}
+
+		if _, ok := baseByTidal[match.TidalID]; ok {
+			result.Conflicts = append(result.Conflicts, spotify.TrackConflict{SpotifyID: spotifyID, TidalID: match.TidalID, Name: spotifyTrack.Name, Reason: "added on spotify but removed from tidal, keeping the addition"})
+		}
+		addedToTidal[match.TidalID] = true
+		addToTidal = append(addToTidal, pendingTrack{SpotifyTrack: spotifyTrack, Match: match})
+	}
+
+	pairedTidal := make(map[string]bool)
+	for _, tidalID := range pairs {
+		pairedTidal[tidalID] = true
+	}
+
+	// tracks added to tidal since the base
+	for _, tidalID := range tidalTrackIDs {
+		if _, ok := baseByTidal[tidalID]; ok || pairedTidal[tidalID] {
+			continue
+		}
+		tidalTrack := tidalTrackMap[tidalID]
+
+		match, reason, err := s.tidalToSpotifyTrack(ctx, tidalTrack, TidalToSpotifyOptions{MatchThreshold: opts.MatchThreshold, DryRun: opts.DryRun})
+		if err != nil {
+			reason = err.Error()
+		}
+		if match == nil {
+			result.Conflicts = append(result.Conflicts, spotify.TrackConflict{TidalID: tidalID, Name: tidalTrack.Title, Reason: "added on tidal but not found on spotify: " + reason})
+			continue
+		}
+
+		// the spotify track is already paired with a different release on tidal
+		if _, ok := pairs[match.SpotifyID]; ok {
+			continue
+		}
+
+		pairs[match.SpotifyID] = tidalID
+		if _, inSpotify := spotifyTrackMap[match.SpotifyID]; inSpotify {
+			continue
+		}
+
+		if _, ok := baseBySpotify[match.SpotifyID]; ok {
+			result.Conflicts = append(result.Conflicts, spotify.TrackConflict{SpotifyID: match.SpotifyID, TidalID: tidalID, Name: tidalTrack.Title, Reason: "added on tidal but removed from spotify, keeping the addition"})
+		}
+		addToSpotify = append(addToSpotify, libSpotify.ID(match.SpotifyID))
+	}
+
+	// removals are cancelled if the track is still paired, e.g. it was replaced on the other side
+	pairedTidal = make(map[string]bool)
+	for _, tidalID := range pairs {
+		pairedTidal[tidalID] = true
+	}
+	var removeTidalIndices []int64
+	var tidalRemovals []PlannedRemoval
+	for i, tidalTrack := range tidalTracks {
+		tidalID := strconv.FormatInt(tidalTrack.ID, 10)
+		if !removeFromTidal[tidalID] || pairedTidal[tidalID] {
+			continue
+		}
+
+		// prefer the item index reported by tidal, falling back to the position in the playlist
+		index := int64(i)
+		if tidalTrack.ItemUUID != "" {
+			index = tidalTrack.Index
+		}
+		removeTidalIndices = append(removeTidalIndices, index)
+		tidalRemovals = append(tidalRemovals, PlannedRemoval{TidalTrackID: tidalID, Name: tidalTrack.Title, Index: index})
+	}
+	var removeSpotifyIDs []libSpotify.ID
+	for _, spotifyID := range spotifyTrackIDs {
+		if _, ok := pairs[spotifyID]; removeFromSpotify[spotifyID] && !ok {
+			removeSpotifyIDs = append(removeSpotifyIDs, libSpotify.ID(spotifyID))
+		}
+	}
+
+	for _, conflict := range result.Conflicts {
+		log.Warn().Str("spotify_playlist_id", spotifyPlaylist.ID.String()).Str("spotify_track_id", conflict.SpotifyID).Str("tidal_track_id", conflict.TidalID).Str("track_name", conflict.Name).Str("reason", conflict.Reason).Msg("reconcile conflict")
+	}
+
+	if opts.DryRun {
+		for _, pending := range addToTidal {
+			playlistPlan.TracksToAdd = append(playlistPlan.TracksToAdd, newPlannedTrack(pending.SpotifyTrack, pending.Match, ""))
+		}
+		for _, spotifyTrack := range result.MissingTracks {
+			playlistPlan.UnresolvedTracks = append(playlistPlan.UnresolvedTracks, newPlannedTrack(spotifyTrack, nil, result.MissingTrackReasons[spotifyTrack.ID.String()]))
+		}
+		playlistPlan.TracksToRemove = tidalRemovals
+		playlistPlan.Conflicts = result.Conflicts
+		for _, spotifyID := range addToSpotify {
+			log.Info().Str("spotify_track_id", spotifyID.String()).Msg("would add track to spotify")
+		}
+		log.Info().Str("spotify_playlist_id", spotifyPlaylist.ID.String()).Msgf("dry run - would add %d tracks to tidal, %d tracks to spotify, remove %d tracks from tidal, %d tracks from spotify, %d missing, %d conflicts", len(addToTidal), len(addToSpotify), len(removeTidalIndices), len(removeSpotifyIDs), len(result.MissingTracks), len(result.Conflicts))
+		return result, nil
+	}
+
+	if len(removeTidalIndices) > 0 {
+		log.Info().Str("tidal_playlist_id", tidalPlaylistID).Msgf("removing %d tracks from tidal playlist", len(removeTidalIndices))
+		if err := s.removeTidalPlaylistItems(ctx, tidalPlaylistID, removeTidalIndices); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(removeSpotifyIDs) > 0 {
+		log.Info().Str("spotify_playlist_id", spotifyPlaylist.ID.String()).Msgf("removing %d tracks from spotify playlist", len(removeSpotifyIDs))
+		if err := s.SpotifyService.RemoveTracksFromPlaylist(ctx, spotifyPlaylist.ID, removeSpotifyIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.addTracksToTidalPlaylist(ctx, dbPlaylist, tidalPlaylistID, addToTidal); err != nil {
+		return nil, err
+	}
+
+	if len(addToSpotify) > 0 {
+		log.Info().Str("spotify_playlist_id", spotifyPlaylist.ID.String()).Msgf("adding %d tracks to spotify playlist", len(addToSpotify))
+		if err := s.SpotifyService.AddTracksToPlaylist(ctx, spotifyPlaylist.ID, addToSpotify); err != nil {
+			return nil, err
+		}
+		for _, spotifyID := range addToSpotify {
+			err := s.Queries.AddTrackToPlaylist(ctx, db.AddTrackToPlaylistParams{
+				PlaylistID: sql.NullString{String: dbPlaylist, Valid: true},
+				TrackID:    sql.NullString{String: spotifyID.String(), Valid: true},
+			})
+			if err != nil {
+				log.Error().Err(err).Str("spotify_track_id", spotifyID.String()).Msgf("error adding track to database")
+			}
+		}
+	}
+
+	// remove tracks that are no longer in the merged playlist from the database
+	for spotifyID := range baseBySpotify {
+		if _, ok := pairs[spotifyID]; ok {
+			continue
+		}
+		err := s.Queries.DeletePlaylistTrack(ctx, db.DeletePlaylistTrackParams{
+			PlaylistID: sql.NullString{String: dbPlaylist, Valid: true},
+			TrackID:    sql.NullString{String: spotifyID, Valid: true},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// record the merged playlist as the base for the next reconciliation
+	if err := s.Queries.DeletePlaylistBaseTracks(ctx, dbPlaylist); err != nil {
+		return nil, err
+	}
+	for spotifyID, tidalID := range pairs {
+		err := s.Queries.AddPlaylistBaseTrack(ctx, db.AddPlaylistBaseTrackParams{
+			PlaylistID: dbPlaylist,
+			SpotifyID:  spotifyID,
+			TidalID:    tidalID,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	log.Info().Str("spotify_playlist", spotifyPlaylist.Name).Msgf("reconcile complete - added %d tracks to tidal, %d tracks to spotify, removed %d tracks from tidal, %d tracks from spotify, %d missing, %d conflicts", len(addToTidal), len(addToSpotify), len(removeTidalIndices), len(removeSpotifyIDs), len(result.MissingTracks), len(result.Conflicts))
+
+	return result, nil
+}
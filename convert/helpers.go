@@ -2,8 +2,8 @@ package convert
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/zibbp/spotify-playlist-sync/tidal"
 	tidal_tracks "github.com/zibbp/spotify-playlist-sync/tidal/tracks"
@@ -12,133 +12,147 @@ import (
 	spotifyPkg "github.com/zmb3/spotify/v2"
 )
 
-// clean up the track name removing everything after the first special character
-func cleanName(trackName string) string {
-	trackName = strings.TrimSpace(strings.Split(trackName, "-")[0])
-	trackName = strings.TrimSpace(strings.Split(trackName, "(")[0])
-	trackName = strings.TrimSpace(strings.Split(trackName, "[")[0])
-	return trackName
-}
+// spotifyToTidalTrack attempts to find the provided spotify track on Tidal.
+// Manual overrides are honored before any lookup, returning errTrackIgnored for ignored tracks.
+// Previously matched tracks are served from the local database. Otherwise tracks are checked by ISRC first, falling back to scoring title/album/artist search results.
+// Tracks that were recently not found are skipped until the retry interval elapses.
+// If the track is not found the reason is returned instead of a match.
+func (s *Service) spotifyToTidalTrack(ctx context.Context, spotifyTrack *spotifyPkg.FullTrack, opts SyncOptions) (*TrackMatch, string, error) {
+	overrideMatch, err := s.trackOverride(ctx, spotifyTrack)
+	if err != nil {
+		return nil, "", err
+	}
+	if overrideMatch != nil {
+		log.Debug().Str("spotify_track_id", spotifyTrack.ID.String()).Str("tidal_track_id", overrideMatch.TidalID).Msg("track override found in database")
+		return overrideMatch, "", nil
+	}
 
-// durationMatch returns a boolean if the provided duration is within 2 seconds.
-func durationMatch(spotifyDuration int, tidalDuration int) bool {
-	// allow for a 2 second difference
-	log.Debug().Msgf("Spotify Duration: %d, Tidal Duration: %d", spotifyDuration, tidalDuration)
-	return spotifyDuration >= tidalDuration-5 && spotifyDuration <= tidalDuration+5
-}
+	cachedMatch, err := s.cachedTrackMatch(ctx, spotifyTrack, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	if cachedMatch != nil {
+		log.Debug().Str("spotify_track_id", spotifyTrack.ID.String()).Str("tidal_track_id", cachedMatch.TidalID).Msg("track mapping found in database")
+		return cachedMatch, "", nil
+	}
 
-func nameMatch(spotifyName string, tidalName string) bool {
-	log.Debug().Msgf("Spotify Name: %s, Tidal Name: %s", spotifyName, tidalName)
-	return strings.Contains(strings.ToLower(tidalName), strings.ToLower(spotifyName))
-}
+	skip, err := s.skipMissingTrack(ctx, spotifyTrack, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	if skip {
+		return nil, "recently not found, skipped until the retry interval elapses", nil
+	}
 
-func artistMatch(spotifyArtists []string, tidalArtists []string) bool {
-	log.Debug().Msgf("Spotify Artists: %v, Tidal Artists: %v", spotifyArtists, tidalArtists)
-	for _, spotifyArtist := range spotifyArtists {
-		found := false
-		for _, tidalArtist := range tidalArtists {
-			if strings.Contains(strings.ToLower(tidalArtist), strings.ToLower(spotifyArtist)) {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
+	match, reason, err := s.searchTidalTrack(ctx, spotifyTrack, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// dry runs do not write to the database
+	if opts.DryRun {
+		return match, reason, nil
+	}
+
+	if match == nil {
+		if err := s.saveMissingTrack(ctx, spotifyTrack); err != nil {
+			return nil, "", err
 		}
+		return nil, reason, nil
+	}
+
+	if err := s.saveTrackMatch(ctx, match); err != nil {
+		return nil, "", err
+	}
+
+	if err := s.Queries.DeleteMissingTrack(ctx, match.SpotifyID); err != nil {
+		return nil, "", err
 	}
-	return true
+
+	return match, "", nil
 }
 
-// spotifyToTidalTrack attempts to find the provided spotify track on Tidal.
-// Tracks are checed by ISRC first, falling back to a more crude title/album/artist search
-func (s *Service) spotifyToTidalTrack(ctx context.Context, spotifyTrack *spotifyPkg.FullTrack) (*tidal_tracks.TracksResource, error) {
+// searchQuery is a Tidal search and the match method recorded for its results.
+type searchQuery struct {
+	query  string
+	method string
+}
+
+// searchTidalTrack looks up the Spotify track using the Tidal API.
+// If no match is found the reason from the last search is returned.
+func (s *Service) searchTidalTrack(ctx context.Context, spotifyTrack *spotifyPkg.FullTrack, opts SyncOptions) (*TrackMatch, string, error) {
 	spotifyIsrc := spotifyTrack.ExternalIDs["isrc"]
 	if spotifyIsrc != "" {
 		// attempt to find the track using the ISRC
-		tidalTrack, err := s.TidalService.GetTrackByISRCv2(ctx, spotifyIsrc)
+		tidalTrack, err := s.TidalService.GetTrackByISRCv2(ctx, spotifyIsrc, opts.CountryCode)
 		if err != nil {
-			if err.Error() == "track not found" {
+			if errors.Is(err, tidal.ErrNotFound) {
 				log.Warn().Str("platform", "tidal").Str("spotify_track_id", spotifyTrack.ID.String()).Str("spotify_track_name", spotifyTrack.Name).Str("spotify_track_isrc", spotifyIsrc).Msgf("track not found via")
 				// continue
 			} else {
-				return nil, err
+				return nil, "", err
 			}
 		}
 		if tidalTrack != nil {
-			return tidalTrack, nil
+			return newTrackMatch(spotifyTrack, tidalTrack, MatchMethodISRC, 1), "", nil
 		}
 	}
 
-	// attempt to find track using name, artists, and duration
-	spotifyArtists := make([]string, 0)
-	for _, artist := range spotifyTrack.Artists {
-		spotifyArtists = append(spotifyArtists, artist.Name)
-	}
-
-	// create a clean track name
+	// attempt to find track by scoring search results on name, artists, album, and duration
 	spotifyName := cleanName(spotifyTrack.Name)
-	// spotifyDuration := spotifyTrack.Duration
 
-	spotifyAlbum := spotifyTrack.Album.Name
-
-	// search #1 using the track and and album
-	query := fmt.Sprintf("%s %s", spotifyName, spotifyAlbum)
-
-	log.Debug().Str("platform", "tidal").Str("query", query).Msg("searching for track")
+	// search #1 using the track and album
+	queries := []searchQuery{
+		{query: fmt.Sprintf("%s %s", spotifyName, spotifyTrack.Album.Name), method: MatchMethodAlbumSearch},
+	}
 
-	tidalSearch, err := s.TidalService.SearchTrackv2(
-		ctx,
-		query,
-		"US",
-	)
-	if err != nil {
-		return nil, err
+	// search #2 using the track name and first artist
+	if len(spotifyTrack.Artists) > 0 {
+		queries = append(queries, searchQuery{query: fmt.Sprintf("%s %s", spotifyName, spotifyTrack.Artists[0].Name), method: MatchMethodArtistSearch})
 	}
 
-	// iterate over list of tidal results to check if we have a match
-	for _, tidalTrack := range *tidalSearch {
-		// check if track meets basic checks
+	// candidates from every search are scored together, stopping once a search yields a match
+	var candidates []tidal.TrackCandidate
+	var reason string
+	candidateMethods := make(map[string]string)
+	for _, q := range queries {
+		log.Debug().Str("platform", "tidal").Str("query", q.query).Msg("searching for track")
 
-		// parse tidal track duration
-		tidalTrackDuration, err := tidal.ParseISODuration(tidalTrack.Attributes.Duration)
+		tidalSearch, err := s.TidalService.SearchTrackCandidatesv2(ctx, q.query, opts.CountryCode)
 		if err != nil {
-			log.Error().Err(err).Msg("failed to parse tidal track duration")
-			continue
+			return nil, "", err
+		}
+
+		for _, candidate := range tidalSearch {
+			if _, ok := candidateMethods[candidate.Track.Id]; ok {
+				continue
+			}
+			candidateMethods[candidate.Track.Id] = q.method
+			candidates = append(candidates, candidate)
 		}
 
-		if nameMatch(spotifyName, tidalTrack.Attributes.Title) && durationMatch(int((spotifyTrack.Duration/1000)), int(tidalTrackDuration.Seconds())) {
-			return &tidalTrack, nil
+		var best *scoredCandidate
+		best, reason = bestCandidate(spotifyTrack, candidates, opts.MatchThreshold)
+		if best != nil {
+			return newTrackMatch(spotifyTrack, &best.Candidate.Track, candidateMethods[best.Candidate.Track.Id], best.Score), "", nil
 		}
 	}
 
-	// search #2 using the track name and first artist
-	query = fmt.Sprintf("%s %s", spotifyName, spotifyArtists[0])
+	log.Info().Str("spotify_track_id", spotifyTrack.ID.String()).Str("spotify_track_name", spotifyTrack.Name).Str("reason", reason).Msg("no tidal match found")
 
-	log.Debug().Str("platform", "tidal").Str("query", query).Msg("searching for track")
+	return nil, reason, nil
+}
 
-	tidalSearch, err = s.TidalService.SearchTrackv2(
-		ctx,
-		query,
-		"US",
-	)
-	if err != nil {
-		return nil, err
+func newTrackMatch(spotifyTrack *spotifyPkg.FullTrack, tidalTrack *tidal_tracks.TracksResource, method string, confidence float64) *TrackMatch {
+	match := &TrackMatch{
+		SpotifyID:  spotifyTrack.ID.String(),
+		Isrc:       spotifyTrack.ExternalIDs["isrc"],
+		TidalID:    tidalTrack.Id,
+		Method:     method,
+		Confidence: confidence,
 	}
-
-	// iterate over list of tidal results to check if we have a match
-	for _, tidalTrack := range *tidalSearch {
-
-		// parse tidal track duration
-		tidalTrackDuration, err := tidal.ParseISODuration(tidalTrack.Attributes.Duration)
-		if err != nil {
-			log.Error().Err(err).Msg("failed to parse tidal track duration")
-			continue
-		}
-
-		if nameMatch(spotifyName, tidalTrack.Attributes.Title) && durationMatch(int((spotifyTrack.Duration/1000)), int(tidalTrackDuration.Seconds())) {
-			return &tidalTrack, nil
-		}
+	if match.Isrc == "" && tidalTrack.Attributes != nil {
+		match.Isrc = tidalTrack.Attributes.Isrc
 	}
-
-	return nil, nil
+	return match
 }
@@ -0,0 +1,197 @@
+package convert
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/zibbp/spotify-playlist-sync/db"
+	libSpotify "github.com/zmb3/spotify/v2"
+
+	"github.com/rs/zerolog/log"
+)
+
+// syncRunCompleted is the status of a finished sync run. Runs that are still "running" were interrupted and can be resumed.
+const syncRunCompleted = "completed"
+
+// sync run item statuses
+const (
+	syncItemPending = "pending"
+	syncItemMatched = "matched"
+	syncItemMissing = "missing"
+	syncItemAdded   = "added"
+)
+
+// syncJournal records the progress of each playlist during a sync run so an interrupted run can be resumed.
+// A nil journal records nothing, which is used for dry runs.
+type syncJournal struct {
+	queries *db.Queries
+	runID   int64
+}
+
+// startSyncJournal resumes the last interrupted sync run if requested, otherwise a new run is started and interrupted runs are abandoned.
+// Runs belong to the sync job they were started by, so each job of a sync file resumes its own run.
+func (s *Service) startSyncJournal(ctx context.Context, opts SyncOptions) (*syncJournal, error) {
+	if opts.DryRun {
+		return nil, nil
+	}
+
+	if opts.Resume {
+		run, err := s.Queries.GetRunningSyncRun(ctx, opts.Job)
+		if err == nil {
+			log.Info().Int64("sync_run_id", run.ID).Str("job", run.Job).Time("started_at", run.StartedAt.Time).Msg("resuming interrupted sync")
+			return &syncJournal{queries: s.Queries, runID: run.ID}, nil
+		}
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+		log.Info().Msg("no interrupted sync to resume, starting a new sync")
+	}
+
+	if err := s.Queries.AbandonSyncRuns(ctx, opts.Job); err != nil {
+		return nil, err
+	}
+	if err := s.Queries.DeleteFinishedSyncRunItems(ctx); err != nil {
+		return nil, err
+	}
+
+	run, err := s.Queries.CreateSyncRun(ctx, opts.Job)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug().Int64("sync_run_id", run.ID).Str("job", run.Job).Msg("started sync run")
+
+	return &syncJournal{queries: s.Queries, runID: run.ID}, nil
+}
+
+// finish marks the run as completed and removes its items.
+func (j *syncJournal) finish(ctx context.Context) error {
+	if j == nil {
+		return nil
+	}
+
+	err := j.queries.FinishSyncRun(ctx, db.FinishSyncRunParams{
+		Status: syncRunCompleted,
+		ID:     j.runID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return j.queries.DeleteFinishedSyncRunItems(ctx)
+}
+
+// enqueue adds the tracks of the playlist that are not journaled yet as pending items.
+// The items of the playlist are returned by Spotify ID, including the results journaled by an interrupted run.
+func (j *syncJournal) enqueue(ctx context.Context, playlistID string, spotifyTracks []*libSpotify.FullTrack) (map[string]db.SyncRunItem, error) {
+	if j == nil {
+		return nil, nil
+	}
+
+	for position, spotifyTrack := range spotifyTracks {
+		err := j.queries.CreateSyncRunItem(ctx, db.CreateSyncRunItemParams{
+			RunID:      j.runID,
+			PlaylistID: playlistID,
+			SpotifyID:  spotifyTrack.ID.String(),
+			Position:   int64(position),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	items, err := j.queries.GetSyncRunItems(ctx, db.GetSyncRunItemsParams{
+		RunID:      j.runID,
+		PlaylistID: playlistID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	itemMap := make(map[string]db.SyncRunItem, len(items))
+	counts := make(map[string]int)
+	for _, item := range items {
+		itemMap[item.SpotifyID] = item
+		counts[item.Status]++
+	}
+
+	if counts[syncItemPending] < len(items) {
+		log.Info().Str("spotify_playlist_id", playlistID).Msgf("resuming playlist - %d matched, %d missing, %d added, %d pending", counts[syncItemMatched], counts[syncItemMissing], counts[syncItemAdded], counts[syncItemPending])
+	}
+
+	return itemMap, nil
+}
+
+// recordResult journals the match of the track, or the reason it was not found if the match is nil.
+func (j *syncJournal) recordResult(ctx context.Context, playlistID string, spotifyID string, match *TrackMatch, reason string) error {
+	if j == nil {
+		return nil
+	}
+
+	params := db.UpdateSyncRunItemParams{
+		Status:     syncItemMissing,
+		Reason:     sql.NullString{String: reason, Valid: reason != ""},
+		RunID:      j.runID,
+		PlaylistID: playlistID,
+		SpotifyID:  spotifyID,
+	}
+	if match != nil {
+		params.Status = syncItemMatched
+		params.TidalID = sql.NullString{String: match.TidalID, Valid: true}
+	}
+
+	return j.queries.UpdateSyncRunItem(ctx, params)
+}
+
+// recordAdded journals that the tracks were added to the Tidal playlist.
+func (j *syncJournal) recordAdded(ctx context.Context, playlistID string, pendingTracks []pendingTrack) error {
+	if j == nil {
+		return nil
+	}
+
+	for _, pending := range pendingTracks {
+		err := j.queries.UpdateSyncRunItem(ctx, db.UpdateSyncRunItemParams{
+			Status:     syncItemAdded,
+			TidalID:    sql.NullString{String: pending.Match.TidalID, Valid: true},
+			RunID:      j.runID,
+			PlaylistID: playlistID,
+			SpotifyID:  pending.SpotifyTrack.ID.String(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// journaledTrackMatch returns the result journaled by an interrupted run so the track is not searched again.
+// Tracks without a result are matched and their result is journaled.
+func (s *Service) journaledTrackMatch(ctx context.Context, journal *syncJournal, playlistID string, items map[string]db.SyncRunItem, spotifyTrack *libSpotify.FullTrack, opts SyncOptions) (*TrackMatch, string, error) {
+	if item, ok := items[spotifyTrack.ID.String()]; ok {
+		switch item.Status {
+		case syncItemMatched, syncItemAdded:
+			log.Debug().Str("spotify_track_id", spotifyTrack.ID.String()).Str("tidal_track_id", item.TidalID.String).Msg("track match found in sync journal")
+			return &TrackMatch{
+				SpotifyID: spotifyTrack.ID.String(),
+				Isrc:      spotifyTrack.ExternalIDs["isrc"],
+				TidalID:   item.TidalID.String,
+			}, "", nil
+		case syncItemMissing:
+			log.Debug().Str("spotify_track_id", spotifyTrack.ID.String()).Msg("missing track found in sync journal")
+			return nil, item.Reason.String, nil
+		}
+	}
+
+	match, reason, err := s.spotifyToTidalTrack(ctx, spotifyTrack, opts)
+	if err != nil {
+		// errors are not journaled so the track is retried when resuming
+		return nil, "", err
+	}
+
+	if err := journal.recordResult(ctx, playlistID, spotifyTrack.ID.String(), match, reason); err != nil {
+		return nil, "", err
+	}
+
+	return match, reason, nil
+}
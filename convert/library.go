@@ -0,0 +1,406 @@
+package convert
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zibbp/spotify-playlist-sync/spotify"
+	"github.com/zibbp/spotify-playlist-sync/tidal"
+	libSpotify "github.com/zmb3/spotify/v2"
+	"golang.org/x/exp/slices"
+
+	"github.com/rs/zerolog/log"
+)
+
+// weights of each component of an album score, summing to 1
+const (
+	albumTitleWeight  = 0.5
+	albumArtistWeight = 0.35
+	albumTracksWeight = 0.15
+)
+
+// savedAlbumsToFavorites adds the Spotify saved albums to the Tidal favorite albums.
+// Albums are matched by UPC first, falling back to scoring title and artist search results. Albums are never removed from the Tidal favorites.
+func (s *Service) savedAlbumsToFavorites(ctx context.Context, opts SyncOptions, plan *Plan) error {
+	savedAlbums, err := s.SpotifyService.GetSavedAlbums(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("platform", "spotify").Msgf("fetched %d saved albums", len(savedAlbums))
+
+	favoriteAlbums, err := s.TidalService.GetFavoriteAlbums(ctx)
+	if err != nil {
+		return err
+	}
+
+	favoriteAlbumMap := make(map[string]bool)
+	for _, favoriteAlbum := range favoriteAlbums.Items {
+		favoriteAlbumMap[strconv.FormatInt(favoriteAlbum.Item.ID, 10)] = true
+	}
+
+	favoritesPlan := &FavoritesPlan{Job: opts.Job, Kind: libraryKindAlbums}
+
+	missingAlbums := spotify.MissingAlbums{Reasons: make(map[string]string)}
+	var tidalAlbumIDs []string
+	for _, spotifyAlbum := range savedAlbums {
+		// stop before the next album when shutting down
+		if ctx.Err() != nil {
+			log.Warn().Msg("saved albums sync interrupted, saving progress")
+			break
+		}
+
+		match, reason, err := s.spotifyToTidalAlbum(context.WithoutCancel(ctx), spotifyAlbum, opts)
+		if err != nil {
+			log.Error().Err(err).Str("spotify_album_id", spotifyAlbum.ID.String()).Str("spotify_album_name", spotifyAlbum.Name).Msg("failed to find album on Tidal")
+			reason = err.Error()
+		}
+		if match == nil {
+			log.Warn().Str("spotify_album_id", spotifyAlbum.ID.String()).Str("spotify_album_name", spotifyAlbum.Name).Str("reason", reason).Msg("album not found")
+			missingAlbums.Albums = append(missingAlbums.Albums, spotifyAlbum)
+			missingAlbums.Reasons[spotifyAlbum.ID.String()] = reason
+			favoritesPlan.Unresolved = append(favoritesPlan.Unresolved, newPlannedAlbum(spotifyAlbum, nil, reason))
+			continue
+		}
+
+		if favoriteAlbumMap[match.TidalID] {
+			continue
+		}
+		favoriteAlbumMap[match.TidalID] = true
+
+		favoritesPlan.ToAdd = append(favoritesPlan.ToAdd, newPlannedAlbum(spotifyAlbum, match, ""))
+		tidalAlbumIDs = append(tidalAlbumIDs, match.TidalID)
+	}
+
+	log.Info().Msgf("saved albums processing complete - %d albums to add, %d missing albums", len(tidalAlbumIDs), len(missingAlbums.Albums))
+
+	if opts.DryRun {
+		plan.Favorites = append(plan.Favorites, favoritesPlan)
+		return nil
+	}
+
+	if len(tidalAlbumIDs) > 0 {
+		// matched albums are still added when shutting down so the progress is not lost
+		if err := s.TidalService.AddFavoriteAlbums(context.WithoutCancel(ctx), tidalAlbumIDs); err != nil {
+			return err
+		}
+	}
+
+	if opts.SaveMissingTracks && len(missingAlbums.Albums) > 0 {
+		if err := spotify.WriteMissingAlbums(missingAlbums, *s.SpotifyService.EnvConfig); err != nil {
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
+
+// libraryMatch returns the stored match of the Spotify album or artist, falling back to find and storing its result.
+// If find does not match the album or artist its reason is returned instead of a match.
+func (s *Service) libraryMatch(ctx context.Context, kind string, spotifyID string, opts SyncOptions, find func() (*LibraryMatch, string, error)) (*LibraryMatch, string, error) {
+	match, err := s.cachedLibraryMatch(ctx, kind, spotifyID)
+	if err != nil {
+		return nil, "", err
+	}
+	if match != nil {
+		log.Debug().Str("kind", kind).Str("spotify_id", spotifyID).Str("tidal_id", match.TidalID).Msg("library mapping found in database")
+		return match, "", nil
+	}
+
+	match, reason, err := find()
+	if err != nil || match == nil {
+		return nil, reason, err
+	}
+
+	// dry runs do not write to the database
+	if !opts.DryRun {
+		if err := s.saveLibraryMatch(ctx, kind, match); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return match, "", nil
+}
+
+// spotifyToTidalAlbum returns the Tidal album matched to the Spotify album, or the reason it was not found.
+// Previously matched albums are served from the local database.
+func (s *Service) spotifyToTidalAlbum(ctx context.Context, spotifyAlbum libSpotify.FullAlbum, opts SyncOptions) (*LibraryMatch, string, error) {
+	return s.libraryMatch(ctx, libraryKindAlbums, spotifyAlbum.ID.String(), opts, func() (*LibraryMatch, string, error) {
+		return s.searchTidalAlbum(ctx, spotifyAlbum, opts)
+	})
+}
+
+// searchTidalAlbum looks up the Spotify album on Tidal by UPC, falling back to scoring title and artist search results.
+func (s *Service) searchTidalAlbum(ctx context.Context, spotifyAlbum libSpotify.FullAlbum, opts SyncOptions) (*LibraryMatch, string, error) {
+	if upc := spotifyAlbum.ExternalIDs["upc"]; upc != "" {
+		candidates, err := s.TidalService.GetAlbumsByBarcodev2(ctx, barcodeVariants(upc), opts.CountryCode)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(candidates) > 0 {
+			return &LibraryMatch{
+				SpotifyID:  spotifyAlbum.ID.String(),
+				TidalID:    candidates[0].Album.Id,
+				Method:     MatchMethodUPC,
+				Confidence: 1,
+			}, "", nil
+		}
+		log.Debug().Str("platform", "tidal").Str("spotify_album_id", spotifyAlbum.ID.String()).Str("upc", upc).Msg("album not found via upc")
+	}
+
+	spotifyName := cleanName(spotifyAlbum.Name)
+	queries := []string{spotifyName}
+	if len(spotifyAlbum.Artists) > 0 {
+		queries = []string{fmt.Sprintf("%s %s", spotifyName, spotifyAlbum.Artists[0].Name), spotifyName}
+	}
+
+	var reason string
+	for _, query := range queries {
+		log.Debug().Str("platform", "tidal").Str("query", query).Msg("searching for album")
+
+		candidates, err := s.TidalService.SearchAlbumCandidatesv2(ctx, query, opts.CountryCode)
+		if err != nil {
+			return nil, "", err
+		}
+
+		scores := make([]candidateScore, 0, len(candidates))
+		for _, candidate := range candidates {
+			score := scoreAlbum(spotifyAlbum, candidate)
+			log.Debug().Str("spotify_album_name", spotifyAlbum.Name).Str("tidal_album_id", candidate.Album.Id).Strs("tidal_artists", candidate.Artists).Float64("score", score).Msg("scored album candidate")
+			scores = append(scores, candidateScore{ID: candidate.Album.Id, Score: score})
+		}
+
+		var best int
+		best, reason = selectBest(scores, opts.MatchThreshold, "tidal")
+		if best >= 0 {
+			return &LibraryMatch{
+				SpotifyID:  spotifyAlbum.ID.String(),
+				TidalID:    candidates[best].Album.Id,
+				Method:     MatchMethodAlbumSearch,
+				Confidence: scores[best].Score,
+			}, "", nil
+		}
+	}
+
+	return nil, reason, nil
+}
+
+// scoreAlbum scores how well the Tidal album matches the Spotify album between 0 and 1.
+func scoreAlbum(spotifyAlbum libSpotify.FullAlbum, candidate tidal.AlbumCandidate) float64 {
+	if candidate.Album.Attributes == nil {
+		return 0
+	}
+
+	spotifyArtists := make([]string, 0, len(spotifyAlbum.Artists))
+	for _, artist := range spotifyAlbum.Artists {
+		spotifyArtists = append(spotifyArtists, artist.Name)
+	}
+
+	score := albumTitleWeight * stringSimilarity(cleanName(spotifyAlbum.Name), cleanName(candidate.Album.Attributes.Title))
+	score += albumArtistWeight * artistOverlap(spotifyArtists, candidate.Artists)
+
+	// deluxe and standard editions share a title but not a track count
+	trackDelta := spotifyAlbum.Tracks.Total - int(candidate.Album.Attributes.NumberOfItems)
+	switch {
+	case trackDelta == 0:
+		score += albumTracksWeight
+	case trackDelta >= -2 && trackDelta <= 2:
+		score += albumTracksWeight / 2
+	}
+
+	return score
+}
+
+// barcodeVariants returns the UPC along with its 12 digit UPC-A and 13 digit EAN forms, as services differ in leading zeros.
+func barcodeVariants(upc string) []string {
+	variants := []string{upc}
+	trimmed := strings.TrimLeft(upc, "0")
+	for _, length := range []int{12, 13} {
+		if len(trimmed) > length {
+			continue
+		}
+		variant := strings.Repeat("0", length-len(trimmed)) + trimmed
+		if variant != upc {
+			variants = append(variants, variant)
+		}
+	}
+	return variants
+}
+
+// followedArtistsToFavorites adds the Spotify followed artists to the Tidal favorite artists.
+// Artists are matched by name and confirmed by their top tracks, see spotifyToTidalArtist. Artists are never removed from the Tidal favorites.
+func (s *Service) followedArtistsToFavorites(ctx context.Context, opts SyncOptions, plan *Plan) error {
+	followedArtists, err := s.SpotifyService.GetFollowedArtists(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("platform", "spotify").Msgf("fetched %d followed artists", len(followedArtists))
+
+	favoriteArtists, err := s.TidalService.GetFavoriteArtists(ctx)
+	if err != nil {
+		return err
+	}
+
+	favoriteArtistMap := make(map[string]bool)
+	for _, favoriteArtist := range favoriteArtists.Items {
+		favoriteArtistMap[strconv.FormatInt(favoriteArtist.Item.ID, 10)] = true
+	}
+
+	favoritesPlan := &FavoritesPlan{Job: opts.Job, Kind: libraryKindArtists}
+
+	missingArtists := spotify.MissingArtists{Reasons: make(map[string]string)}
+	var tidalArtistIDs []string
+	for _, spotifyArtist := range followedArtists {
+		// stop before the next artist when shutting down
+		if ctx.Err() != nil {
+			log.Warn().Msg("followed artists sync interrupted, saving progress")
+			break
+		}
+
+		match, reason, err := s.spotifyToTidalArtist(context.WithoutCancel(ctx), spotifyArtist, opts)
+		if err != nil {
+			log.Error().Err(err).Str("spotify_artist_id", spotifyArtist.ID.String()).Str("spotify_artist_name", spotifyArtist.Name).Msg("failed to find artist on Tidal")
+			reason = err.Error()
+		}
+		if match == nil {
+			log.Warn().Str("spotify_artist_id", spotifyArtist.ID.String()).Str("spotify_artist_name", spotifyArtist.Name).Str("reason", reason).Msg("artist not found")
+			missingArtists.Artists = append(missingArtists.Artists, spotifyArtist)
+			missingArtists.Reasons[spotifyArtist.ID.String()] = reason
+			favoritesPlan.Unresolved = append(favoritesPlan.Unresolved, PlannedFavorite{SpotifyID: spotifyArtist.ID.String(), Name: spotifyArtist.Name, Reason: reason})
+			continue
+		}
+
+		if favoriteArtistMap[match.TidalID] {
+			continue
+		}
+		favoriteArtistMap[match.TidalID] = true
+
+		favoritesPlan.ToAdd = append(favoritesPlan.ToAdd, PlannedFavorite{SpotifyID: spotifyArtist.ID.String(), Name: spotifyArtist.Name, Match: match})
+		tidalArtistIDs = append(tidalArtistIDs, match.TidalID)
+	}
+
+	log.Info().Msgf("followed artists processing complete - %d artists to add, %d missing artists", len(tidalArtistIDs), len(missingArtists.Artists))
+
+	if opts.DryRun {
+		plan.Favorites = append(plan.Favorites, favoritesPlan)
+		return nil
+	}
+
+	if len(tidalArtistIDs) > 0 {
+		// matched artists are still added when shutting down so the progress is not lost
+		if err := s.TidalService.AddFavoriteArtists(context.WithoutCancel(ctx), tidalArtistIDs); err != nil {
+			return err
+		}
+	}
+
+	if opts.SaveMissingTracks && len(missingArtists.Artists) > 0 {
+		if err := spotify.WriteMissingArtists(missingArtists, *s.SpotifyService.EnvConfig); err != nil {
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
+
+// number of the Spotify artist's top tracks looked up by ISRC to confirm a Tidal artist
+const artistTopTracksChecked = 5
+
+// spotifyToTidalArtist returns the Tidal artist matched to the Spotify artist, or the reason it was not found.
+// Previously matched artists are served from the local database.
+func (s *Service) spotifyToTidalArtist(ctx context.Context, spotifyArtist libSpotify.FullArtist, opts SyncOptions) (*LibraryMatch, string, error) {
+	return s.libraryMatch(ctx, libraryKindArtists, spotifyArtist.ID.String(), opts, func() (*LibraryMatch, string, error) {
+		return s.searchTidalArtist(ctx, spotifyArtist, opts)
+	})
+}
+
+// searchTidalArtist searches Tidal for the Spotify artist.
+// A single search result with exactly the same name is accepted. Otherwise the search results with the same name,
+// or that score at least the match threshold, are only accepted if they are an artist of one of the Spotify artist's top tracks on Tidal.
+func (s *Service) searchTidalArtist(ctx context.Context, spotifyArtist libSpotify.FullArtist, opts SyncOptions) (*LibraryMatch, string, error) {
+	log.Debug().Str("platform", "tidal").Str("query", spotifyArtist.Name).Msg("searching for artist")
+
+	candidates, err := s.TidalService.SearchArtistsv2(ctx, spotifyArtist.Name, opts.CountryCode)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var exact []string
+	scores := make([]candidateScore, 0, len(candidates))
+	for _, candidate := range candidates {
+		var score float64
+		if candidate.Attributes != nil {
+			score = stringSimilarity(spotifyArtist.Name, candidate.Attributes.Name)
+			if normalize(candidate.Attributes.Name) == normalize(spotifyArtist.Name) {
+				exact = append(exact, candidate.Id)
+			}
+		}
+		scores = append(scores, candidateScore{ID: candidate.Id, Score: score})
+	}
+
+	if len(exact) == 1 {
+		return &LibraryMatch{SpotifyID: spotifyArtist.ID.String(), TidalID: exact[0], Method: MatchMethodExactName, Confidence: 1}, "", nil
+	}
+
+	// candidates are checked in order of search relevance
+	possible := exact
+	reason := fmt.Sprintf("%d artists named %q, none found by the isrc of a top track", len(exact), spotifyArtist.Name)
+	if len(possible) == 0 {
+		if best, bestReason := selectBest(scores, opts.MatchThreshold, "tidal"); best < 0 {
+			return nil, bestReason, nil
+		}
+		for _, score := range scores {
+			if score.Score >= opts.MatchThreshold {
+				possible = append(possible, score.ID)
+			}
+		}
+		reason = "no artist with an exact name match, none found by the isrc of a top track"
+	}
+
+	tidalArtistID, err := s.confirmArtistByTopTracks(ctx, spotifyArtist, possible, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	if tidalArtistID == "" {
+		return nil, reason, nil
+	}
+
+	return &LibraryMatch{SpotifyID: spotifyArtist.ID.String(), TidalID: tidalArtistID, Method: MatchMethodTopTrack, Confidence: 1}, "", nil
+}
+
+// confirmArtistByTopTracks returns the first of the Tidal artists that is an artist of one of the Spotify artist's top tracks on Tidal.
+// An empty ID is returned if none of the top tracks confirm an artist.
+func (s *Service) confirmArtistByTopTracks(ctx context.Context, spotifyArtist libSpotify.FullArtist, tidalArtistIDs []string, opts SyncOptions) (string, error) {
+	topTracks, err := s.SpotifyService.GetArtistTopTracks(ctx, spotifyArtist.ID, opts.CountryCode)
+	if err != nil {
+		return "", err
+	}
+
+	checked := 0
+	for _, topTrack := range topTracks {
+		isrc := topTrack.ExternalIDs["isrc"]
+		if isrc == "" {
+			continue
+		}
+		if checked == artistTopTracksChecked {
+			break
+		}
+		checked++
+
+		trackArtistIDs, err := s.TidalService.GetArtistIDsByISRCv2(ctx, isrc, opts.CountryCode)
+		if err != nil {
+			return "", err
+		}
+
+		for _, tidalArtistID := range tidalArtistIDs {
+			if slices.Contains(trackArtistIDs, tidalArtistID) {
+				log.Debug().Str("spotify_artist_id", spotifyArtist.ID.String()).Str("tidal_artist_id", tidalArtistID).Str("isrc", isrc).Msg("artist confirmed by top track")
+				return tidalArtistID, nil
+			}
+		}
+	}
+
+	return "", nil
+}
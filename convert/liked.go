@@ -0,0 +1,130 @@
+package convert
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/zibbp/spotify-playlist-sync/spotify"
+	libSpotify "github.com/zmb3/spotify/v2"
+
+	"github.com/rs/zerolog/log"
+)
+
+// destinations for the Spotify Liked Songs
+const (
+	LikedSongsFavorites = "favorites"
+	LikedSongsPlaylist  = "playlist"
+)
+
+// likedSongsPlaylistID identifies the Spotify Liked Songs, which are not a playlist, in the database and the Tidal playlist description.
+const likedSongsPlaylistID = "spotify-liked-songs"
+
+func likedSongsPlaylist() libSpotify.SimplePlaylist {
+	return libSpotify.SimplePlaylist{
+		ID:          likedSongsPlaylistID,
+		Name:        "Liked Songs",
+		Description: "Spotify Liked Songs",
+	}
+}
+
+// spotifyPlaylistTracks returns the tracks of the Spotify playlist, including the Liked Songs.
+func (s *Service) spotifyPlaylistTracks(ctx context.Context, spotifyPlaylist libSpotify.SimplePlaylist) ([]*libSpotify.FullTrack, error) {
+	if spotifyPlaylist.ID == likedSongsPlaylistID {
+		return s.SpotifyService.GetLikedTracks(ctx)
+	}
+	return s.SpotifyService.GetPlaylistTracks(ctx, spotifyPlaylist.ID)
+}
+
+// likedTracksToFavorites adds the Spotify Liked Songs to the Tidal favorite tracks.
+// Tracks are never removed from the Tidal favorites.
+func (s *Service) likedTracksToFavorites(ctx context.Context, opts SyncOptions, plan *Plan) error {
+	likedTracks, err := s.SpotifyService.GetLikedTracks(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("platform", "spotify").Msgf("fetched %d liked tracks", len(likedTracks))
+
+	favoriteTracks, err := s.TidalService.GetFavoriteTracks(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("platform", "tidal").Msgf("fetched %d favorite tracks", len(favoriteTracks.Items))
+
+	favoriteTrackMap := make(map[string]bool)
+	for _, favoriteTrack := range favoriteTracks.Items {
+		favoriteTrackMap[strconv.FormatInt(favoriteTrack.Item.ID, 10)] = true
+	}
+
+	favoritesPlan := &PlaylistPlan{
+		SpotifyPlaylistID: likedSongsPlaylistID,
+		Name:              "Liked Songs (Tidal favorites)",
+	}
+
+	var missingTracks []*libSpotify.FullTrack
+	missingTrackReasons := make(map[string]string)
+	var tidalTrackIDs []string
+
+	// attempt to find the tracks concurrently, the results are handled in the order they were liked
+	err = matchTracks(ctx, likedTracks, opts.Concurrency, func(ctx context.Context, spotifyTrack *libSpotify.FullTrack) (*TrackMatch, string, error) {
+		return s.spotifyToTidalTrack(ctx, spotifyTrack, opts)
+	}, func(spotifyTrack *libSpotify.FullTrack, result matchResult) error {
+		tidalTrack, reason, err := result.Match, result.Reason, result.Err
+		if errors.Is(err, errTrackIgnored) {
+			log.Debug().Str("spotify_track_id", spotifyTrack.ID.String()).Str("spotify_track_name", spotifyTrack.Name).Msg("track is ignored, skipping")
+			return nil
+		}
+		if err != nil {
+			log.Error().Err(err).Str("spotify_track_id", spotifyTrack.ID.String()).Str("spotify_track_name", spotifyTrack.Name).Str("spotify_track_isrc", spotifyTrack.ExternalIDs["isrc"]).Msgf("failed to find track on Tidal")
+			reason = err.Error()
+		}
+		if tidalTrack == nil {
+			missingTracks = append(missingTracks, spotifyTrack)
+			missingTrackReasons[spotifyTrack.ID.String()] = reason
+			favoritesPlan.UnresolvedTracks = append(favoritesPlan.UnresolvedTracks, newPlannedTrack(spotifyTrack, nil, reason))
+			return nil
+		}
+
+		if favoriteTrackMap[tidalTrack.TidalID] {
+			return nil
+		}
+		favoriteTrackMap[tidalTrack.TidalID] = true
+
+		favoritesPlan.TracksToAdd = append(favoritesPlan.TracksToAdd, newPlannedTrack(spotifyTrack, tidalTrack, ""))
+		tidalTrackIDs = append(tidalTrackIDs, tidalTrack.TidalID)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		favoritesPlan.Job = opts.Job
+		plan.Playlists = append(plan.Playlists, favoritesPlan)
+		return nil
+	}
+
+	if len(tidalTrackIDs) > 0 {
+		log.Info().Msgf("adding %d tracks to tidal favorites", len(tidalTrackIDs))
+		// matched tracks are still added when shutting down so the progress is not lost
+		if err := s.TidalService.AddFavoriteTracks(context.WithoutCancel(ctx), tidalTrackIDs); err != nil {
+			return err
+		}
+	}
+
+	if opts.SaveMissingTracks && len(missingTracks) > 0 {
+		log.Info().Msgf("liked songs processing complete - found %d missing tracks", len(missingTracks))
+		err := spotify.WriteMissingTracks(likedSongsPlaylistID, spotify.MissingTracks{
+			Playlist: likedSongsPlaylist(),
+			Tracks:   missingTracks,
+			Reasons:  missingTrackReasons,
+		}, *s.SpotifyService.EnvConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,161 @@
+package convert
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/zibbp/spotify-playlist-sync/db"
+	spotifyPkg "github.com/zmb3/spotify/v2"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	MatchMethodISRC         = "isrc"
+	MatchMethodAlbumSearch  = "album_search"
+	MatchMethodArtistSearch = "artist_search"
+	MatchMethodManual       = "manual"
+	MatchMethodUPC          = "upc"
+	MatchMethodExactName    = "exact_name"
+	MatchMethodTopTrack     = "top_track_isrc"
+)
+
+// kinds of library mappings
+const (
+	libraryKindAlbums  = "albums"
+	libraryKindArtists = "artists"
+)
+
+// TrackMatch is a resolved Spotify to Tidal track mapping.
+type TrackMatch struct {
+	SpotifyID  string  `json:"spotify_id"`
+	Isrc       string  `json:"isrc"`
+	TidalID    string  `json:"tidal_id"`
+	Method     string  `json:"method"`
+	Confidence float64 `json:"confidence"`
+}
+
+// LibraryMatch is a resolved Spotify to Tidal album or artist mapping.
+type LibraryMatch struct {
+	SpotifyID  string  `json:"spotify_id"`
+	TidalID    string  `json:"tidal_id"`
+	Method     string  `json:"method"`
+	Confidence float64 `json:"confidence"`
+}
+
+// cachedLibraryMatch returns a previously stored mapping for the Spotify album or artist, or nil if there is none.
+func (s *Service) cachedLibraryMatch(ctx context.Context, kind string, spotifyID string) (*LibraryMatch, error) {
+	mapping, err := s.Queries.GetLibraryMapping(ctx, db.GetLibraryMappingParams{Kind: kind, SpotifyID: spotifyID})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &LibraryMatch{
+		SpotifyID:  mapping.SpotifyID,
+		TidalID:    mapping.TidalID,
+		Method:     mapping.MatchMethod,
+		Confidence: mapping.Confidence,
+	}, nil
+}
+
+func (s *Service) saveLibraryMatch(ctx context.Context, kind string, match *LibraryMatch) error {
+	log.Debug().Str("kind", kind).Str("spotify_id", match.SpotifyID).Str("tidal_id", match.TidalID).Str("method", match.Method).Msg("saving library mapping")
+	return s.Queries.UpsertLibraryMapping(ctx, db.UpsertLibraryMappingParams{
+		Kind:        kind,
+		SpotifyID:   match.SpotifyID,
+		TidalID:     match.TidalID,
+		MatchMethod: match.Method,
+		Confidence:  match.Confidence,
+	})
+}
+
+// cachedTrackMatch returns a previously stored mapping for the Spotify track.
+// The Spotify ID is checked first, falling back to the ISRC so the same recording released on different albums is only looked up once.
+func (s *Service) cachedTrackMatch(ctx context.Context, spotifyTrack *spotifyPkg.FullTrack, opts SyncOptions) (*TrackMatch, error) {
+	mapping, err := s.Queries.GetTrackMapping(ctx, spotifyTrack.ID.String())
+	if err == nil {
+		return trackMatchFromMapping(spotifyTrack.ID.String(), mapping), nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	spotifyIsrc := spotifyTrack.ExternalIDs["isrc"]
+	if spotifyIsrc == "" {
+		return nil, nil
+	}
+
+	mapping, err = s.Queries.GetTrackMappingByIsrc(ctx, sql.NullString{String: spotifyIsrc, Valid: true})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	match := trackMatchFromMapping(spotifyTrack.ID.String(), mapping)
+
+	// store the mapping under this spotify id as well
+	if !opts.DryRun {
+		if err := s.saveTrackMatch(ctx, match); err != nil {
+			return nil, err
+		}
+	}
+
+	return match, nil
+}
+
+// saveTrackMatch stores the mapping so subsequent lookups skip the Tidal API.
+func (s *Service) saveTrackMatch(ctx context.Context, match *TrackMatch) error {
+	log.Debug().Str("spotify_track_id", match.SpotifyID).Str("tidal_track_id", match.TidalID).Str("method", match.Method).Msg("saving track mapping")
+	return s.Queries.UpsertTrackMapping(ctx, db.UpsertTrackMappingParams{
+		SpotifyID:   match.SpotifyID,
+		Isrc:        sql.NullString{String: match.Isrc, Valid: match.Isrc != ""},
+		TidalID:     match.TidalID,
+		MatchMethod: match.Method,
+		Confidence:  match.Confidence,
+	})
+}
+
+func trackMatchFromMapping(spotifyID string, mapping db.TrackMapping) *TrackMatch {
+	return &TrackMatch{
+		SpotifyID:  spotifyID,
+		Isrc:       mapping.Isrc.String,
+		TidalID:    mapping.TidalID,
+		Method:     mapping.MatchMethod,
+		Confidence: mapping.Confidence,
+	}
+}
+
+// skipMissingTrack returns true if the track was recently not found on Tidal and the retry interval has not elapsed.
+func (s *Service) skipMissingTrack(ctx context.Context, spotifyTrack *spotifyPkg.FullTrack, opts SyncOptions) (bool, error) {
+	if opts.RecheckMissingTracks {
+		return false, nil
+	}
+
+	missingTrack, err := s.Queries.GetMissingTrack(ctx, spotifyTrack.ID.String())
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if !missingTrack.LastCheckedAt.Valid || time.Since(missingTrack.LastCheckedAt.Time) >= opts.MissingTrackRetryInterval {
+		return false, nil
+	}
+
+	log.Debug().Str("spotify_track_id", spotifyTrack.ID.String()).Str("spotify_track_name", spotifyTrack.Name).Int64("attempts", missingTrack.Attempts).Time("last_checked_at", missingTrack.LastCheckedAt.Time).Msg("track was recently not found, skipping search")
+
+	return true, nil
+}
+
+// saveMissingTrack records that the track could not be found on Tidal.
+func (s *Service) saveMissingTrack(ctx context.Context, spotifyTrack *spotifyPkg.FullTrack) error {
+	spotifyIsrc := spotifyTrack.ExternalIDs["isrc"]
+	return s.Queries.UpsertMissingTrack(ctx, db.UpsertMissingTrackParams{
+		SpotifyID: spotifyTrack.ID.String(),
+		Isrc:      sql.NullString{String: spotifyIsrc, Valid: spotifyIsrc != ""},
+	})
+}
@@ -0,0 +1,260 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/zibbp/spotify-playlist-sync/tidal"
+	spotifyPkg "github.com/zmb3/spotify/v2"
+
+	"github.com/rs/zerolog/log"
+)
+
+// weights of each component of a candidate score, summing to 1
+const (
+	titleWeight    = 0.35
+	artistWeight   = 0.25
+	albumWeight    = 0.10
+	durationWeight = 0.20
+	explicitWeight = 0.05
+	versionWeight  = 0.05
+)
+
+// candidates with a duration difference larger than this are rejected
+const maxDurationDelta = 15
+
+// scoredCandidate is a Tidal candidate and its match score against a Spotify track.
+type scoredCandidate struct {
+	Candidate tidal.TrackCandidate
+	Score     float64
+	// VersionMismatch is set if the candidate is a different version of the track, rejecting it
+	VersionMismatch string
+}
+
+// trackFields are the attributes of a track on either service that are compared when scoring.
+type trackFields struct {
+	Title string
+	// Version is a separate version attribute, e.g. Tidal's "Live at Wembley"
+	Version  string
+	Artists  []string
+	Album    string
+	Duration float64
+	Explicit bool
+}
+
+func spotifyTrackFields(track *spotifyPkg.FullTrack) trackFields {
+	fields := trackFields{
+		Title:    track.Name,
+		Album:    track.Album.Name,
+		Duration: float64(track.Duration / 1000),
+		Explicit: track.Explicit,
+	}
+	for _, artist := range track.Artists {
+		fields.Artists = append(fields.Artists, artist.Name)
+	}
+	return fields
+}
+
+// candidateScore is the score of a candidate identified by its track ID.
+type candidateScore struct {
+	ID              string
+	Score           float64
+	VersionMismatch string
+}
+
+// bestCandidate scores every candidate against the Spotify track and returns the highest scoring one.
+// If no candidate scores at least the threshold, nil is returned with the reason no match was found.
+func bestCandidate(spotifyTrack *spotifyPkg.FullTrack, candidates []tidal.TrackCandidate, threshold float64) (*scoredCandidate, string) {
+	scored := make([]*scoredCandidate, 0, len(candidates))
+	scores := make([]candidateScore, 0, len(candidates))
+	for _, candidate := range candidates {
+		scoredCandidate := scoreCandidate(spotifyTrack, candidate)
+		log.Debug().Str("spotify_track_name", spotifyTrack.Name).Str("tidal_track_id", candidate.Track.Id).Str("tidal_track_name", candidateTitle(candidate)).Strs("tidal_artists", candidate.Artists).Str("tidal_album", candidate.Album).Float64("score", scoredCandidate.Score).Str("version_mismatch", scoredCandidate.VersionMismatch).Msg("scored candidate")
+
+		scored = append(scored, scoredCandidate)
+		scores = append(scores, candidateScore{ID: candidate.Track.Id, Score: scoredCandidate.Score, VersionMismatch: scoredCandidate.VersionMismatch})
+	}
+
+	best, reason := selectBest(scores, threshold, "tidal")
+	if best < 0 {
+		return nil, reason
+	}
+	return scored[best], ""
+}
+
+// selectBest returns the index of the highest scoring candidate without a version mismatch.
+// If no candidate scores at least the threshold, -1 is returned with the reason no match was found.
+func selectBest(scores []candidateScore, threshold float64, platform string) (int, string) {
+	if len(scores) == 0 {
+		return -1, "no search results"
+	}
+
+	best, bestRejected := -1, -1
+	for i, score := range scores {
+		if score.VersionMismatch != "" {
+			if bestRejected < 0 || score.Score > scores[bestRejected].Score {
+				bestRejected = i
+			}
+			continue
+		}
+
+		if best < 0 || score.Score > scores[best].Score {
+			best = i
+		}
+	}
+
+	if best >= 0 && scores[best].Score >= threshold {
+		return best, ""
+	}
+
+	// report a version mismatch if it is the only reason the candidate was rejected
+	if bestRejected >= 0 && scores[bestRejected].Score >= threshold {
+		return -1, fmt.Sprintf("%s (%s track %s)", scores[bestRejected].VersionMismatch, platform, scores[bestRejected].ID)
+	}
+
+	if best >= 0 {
+		return -1, fmt.Sprintf("best candidate scored %.2f, below the threshold of %.2f (%s track %s)", scores[best].Score, threshold, platform, scores[best].ID)
+	}
+
+	return -1, "no candidate scored above zero"
+}
+
+// scoreCandidate scores how well the Tidal candidate matches the Spotify track between 0 and 1.
+// The score of a candidate with a different version is calculated as if the versions matched.
+func scoreCandidate(spotifyTrack *spotifyPkg.FullTrack, candidate tidal.TrackCandidate) *scoredCandidate {
+	scored := &scoredCandidate{Candidate: candidate}
+	if candidate.Track.Attributes == nil {
+		return scored
+	}
+
+	tidalDuration, err := tidal.ParseISODuration(candidate.Track.Attributes.Duration)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to parse tidal track duration")
+		return scored
+	}
+
+	scored.Score, scored.VersionMismatch = scoreTrack(spotifyTrackFields(spotifyTrack), trackFields{
+		Title:    candidate.Track.Attributes.Title,
+		Version:  stringValue(candidate.Track.Attributes.Version),
+		Artists:  candidate.Artists,
+		Album:    candidate.Album,
+		Duration: tidalDuration.Seconds(),
+		Explicit: candidate.Track.Attributes.Explicit,
+	})
+	return scored
+}
+
+// scoreTrack scores how well a Spotify and a Tidal track match between 0 and 1, and returns the reason if their versions differ.
+func scoreTrack(spotifyTrack trackFields, tidalTrack trackFields) (float64, string) {
+	durationDelta := math.Abs(spotifyTrack.Duration - tidalTrack.Duration)
+	if durationDelta > maxDurationDelta {
+		return 0, ""
+	}
+
+	score := titleWeight * stringSimilarity(cleanName(spotifyTrack.Title), cleanName(tidalTrack.Title))
+	score += artistWeight * artistOverlap(spotifyTrack.Artists, tidalTrack.Artists)
+	score += albumWeight * albumSimilarity(spotifyTrack.Album, tidalTrack.Album)
+	score += durationWeight * durationScore(durationDelta)
+	if spotifyTrack.Explicit == tidalTrack.Explicit {
+		score += explicitWeight
+	}
+
+	// versions must agree, e.g. a live recording never matches the studio original
+	// compatible versions that are not identical, e.g. a remaster of the original, score lower
+	spotifyVersion := parseVersion(spotifyTrack.Title, spotifyTrack.Version)
+	tidalVersion := parseVersion(tidalTrack.Title, tidalTrack.Version)
+	if spotifyVersion.String() == tidalVersion.String() {
+		score += versionWeight
+	} else {
+		score += versionWeight / 2
+	}
+
+	return score, versionMismatch(spotifyVersion, tidalVersion)
+}
+
+// candidateTitle returns the Tidal title including its version, e.g. "Song (Live)".
+func candidateTitle(candidate tidal.TrackCandidate) string {
+	if candidate.Track.Attributes == nil {
+		return ""
+	}
+	title := candidate.Track.Attributes.Title
+	if version := stringValue(candidate.Track.Attributes.Version); version != "" {
+		title += " (" + version + ")"
+	}
+	return title
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// durationScore is 1 for durations within 2 seconds, decreasing linearly to 0 at the maximum delta.
+func durationScore(delta float64) float64 {
+	if delta <= 2 {
+		return 1
+	}
+	return math.Max(0, 1-(delta-2)/(maxDurationDelta-2))
+}
+
+// artistOverlap returns the fraction of Spotify artists found in the Tidal artists.
+// Candidates without artist information score neutrally.
+func artistOverlap(spotifyArtists []string, tidalArtists []string) float64 {
+	if len(spotifyArtists) == 0 || len(tidalArtists) == 0 {
+		return 0.5
+	}
+
+	found := 0
+	for _, spotifyArtist := range spotifyArtists {
+		for _, tidalArtist := range tidalArtists {
+			if stringSimilarity(spotifyArtist, tidalArtist) >= 0.8 {
+				found++
+				break
+			}
+		}
+	}
+
+	return float64(found) / float64(len(spotifyArtists))
+}
+
+// albumSimilarity compares album titles, scoring neutrally if the Tidal album is unknown.
+func albumSimilarity(spotifyAlbum string, tidalAlbum string) float64 {
+	if tidalAlbum == "" {
+		return 0.5
+	}
+	return stringSimilarity(cleanName(spotifyAlbum), cleanName(tidalAlbum))
+}
+
+// stringSimilarity returns the Dice coefficient of the words in both strings.
+func stringSimilarity(a string, b string) float64 {
+	aWords := words(a)
+	bWords := words(b)
+	if len(aWords) == 0 && len(bWords) == 0 {
+		return 1
+	}
+	if len(aWords) == 0 || len(bWords) == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int)
+	for _, word := range aWords {
+		counts[word]++
+	}
+	common := 0
+	for _, word := range bWords {
+		if counts[word] > 0 {
+			counts[word]--
+			common++
+		}
+	}
+
+	return 2 * float64(common) / float64(len(aWords)+len(bWords))
+}
+
+// words splits a string into normalized words.
+func words(s string) []string {
+	return strings.Fields(normalize(s))
+}
@@ -0,0 +1,113 @@
+package convert
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestStringSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want float64
+	}{
+		{"equal", "Bohemian Rhapsody", "Bohemian Rhapsody", 1},
+		{"normalized", "Beyoncé", "BEYONCE", 1},
+		{"half the words", "Hello World", "Hello", 2.0 / 3},
+		{"different", "Hello", "Goodbye", 0},
+		{"both empty", "", "", 1},
+		{"one empty", "Hello", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stringSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("stringSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreTrack(t *testing.T) {
+	spotifyTrack := trackFields{
+		Title:    "Hallelujah",
+		Artists:  []string{"Jeff Buckley"},
+		Album:    "Grace",
+		Duration: 413,
+	}
+
+	tests := []struct {
+		name     string
+		tidal    trackFields
+		want     float64
+		mismatch bool
+	}{
+		{"identical", trackFields{Title: "Hallelujah", Artists: []string{"Jeff Buckley"}, Album: "Grace", Duration: 413}, 1, false},
+		{"within 2 seconds", trackFields{Title: "Hallelujah", Artists: []string{"Jeff Buckley"}, Album: "Grace", Duration: 415}, 1, false},
+		{"at the maximum duration delta", trackFields{Title: "Hallelujah", Artists: []string{"Jeff Buckley"}, Album: "Grace", Duration: 413 + maxDurationDelta}, 1 - durationWeight, false},
+		{"beyond the maximum duration delta", trackFields{Title: "Hallelujah", Artists: []string{"Jeff Buckley"}, Album: "Grace", Duration: 413 + maxDurationDelta + 1}, 0, false},
+		{"explicit differs", trackFields{Title: "Hallelujah", Artists: []string{"Jeff Buckley"}, Album: "Grace", Duration: 413, Explicit: true}, 1 - explicitWeight, false},
+		{"remaster", trackFields{Title: "Hallelujah", Version: "Remastered", Artists: []string{"Jeff Buckley"}, Album: "Grace", Duration: 413}, 1 - versionWeight/2, false},
+		{"live", trackFields{Title: "Hallelujah", Version: "Live", Artists: []string{"Jeff Buckley"}, Album: "Grace", Duration: 413}, 1 - versionWeight/2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, mismatch := scoreTrack(spotifyTrack, tt.tidal)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("scoreTrack() = %v, want %v", got, tt.want)
+			}
+			if (mismatch != "") != tt.mismatch {
+				t.Errorf("scoreTrack() mismatch = %q, want mismatch %v", mismatch, tt.mismatch)
+			}
+		})
+	}
+}
+
+func TestSelectBest(t *testing.T) {
+	tests := []struct {
+		name      string
+		scores    []candidateScore
+		threshold float64
+		want      int
+		reason    bool
+	}{
+		{"no candidates", nil, 0.7, -1, true},
+		{"highest score", []candidateScore{{ID: "1", Score: 0.75}, {ID: "2", Score: 0.9}}, 0.7, 1, false},
+		{"below threshold", []candidateScore{{ID: "1", Score: 0.6}, {ID: "2", Score: 0.69}}, 0.7, -1, true},
+		{"at threshold", []candidateScore{{ID: "1", Score: 0.7}}, 0.7, 0, false},
+		{"tie keeps the first result", []candidateScore{{ID: "1", Score: 0.8}, {ID: "2", Score: 0.8}}, 0.7, 0, false},
+		{"version mismatch loses to the right version", []candidateScore{{ID: "1", Score: 0.95, VersionMismatch: "version mismatch"}, {ID: "2", Score: 0.8}}, 0.7, 1, false},
+		{"only version mismatches", []candidateScore{{ID: "1", Score: 0.95, VersionMismatch: "version mismatch"}}, 0.7, -1, true},
+		{"all zero", []candidateScore{{ID: "1", Score: 0}}, 0.7, -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := selectBest(tt.scores, tt.threshold, "tidal")
+			if got != tt.want {
+				t.Errorf("selectBest() = %d, want %d", got, tt.want)
+			}
+			if (reason != "") != tt.reason {
+				t.Errorf("selectBest() reason = %q, want reason %v", reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestScoreTrackWrongVersionLoses(t *testing.T) {
+	spotifyTrack := trackFields{Title: "Hallelujah - Live at Sin-é", Artists: []string{"Jeff Buckley"}, Album: "Live at Sin-é", Duration: 400}
+	studio := trackFields{Title: "Hallelujah", Artists: []string{"Jeff Buckley"}, Album: "Grace", Duration: 400}
+	live := trackFields{Title: "Hallelujah", Version: "Live at Sin-é", Artists: []string{"Jeff Buckley"}, Album: "Live at Sin-é", Duration: 405}
+
+	var scores []candidateScore
+	for i, candidate := range []trackFields{studio, live} {
+		score, mismatch := scoreTrack(spotifyTrack, candidate)
+		scores = append(scores, candidateScore{ID: strconv.Itoa(i), Score: score, VersionMismatch: mismatch})
+	}
+
+	if got, reason := selectBest(scores, 0.7, "tidal"); got != 1 {
+		t.Errorf("selectBest() = %d (%s), want the live version", got, reason)
+	}
+}
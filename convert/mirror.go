@@ -0,0 +1,145 @@
+package convert
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+
+	"github.com/zibbp/spotify-playlist-sync/db"
+	"github.com/zibbp/spotify-playlist-sync/tidal"
+	libSpotify "github.com/zmb3/spotify/v2"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maximum number of items removed from a Tidal playlist in a single request
+const mirrorRemoveChunkSize = 50
+
+// mirrorPlaylist removes tracks from the Tidal playlist and local database that are no longer in the Spotify playlist.
+func (s *Service) mirrorPlaylist(ctx context.Context, dbPlaylist string, tidalPlaylistID string, spotifyTracks []*libSpotify.FullTrack) error {
+	spotifyTrackMap := make(map[string]bool)
+	for _, spotifyTrack := range spotifyTracks {
+		if spotifyTrack != nil {
+			spotifyTrackMap[spotifyTrack.ID.String()] = true
+		}
+	}
+
+	// remove tracks from the database that are no longer in the spotify playlist
+	dbPlaylistTracks, err := s.Queries.GetPlaylistTracks(ctx, sql.NullString{String: dbPlaylist, Valid: true})
+	if err != nil {
+		return err
+	}
+
+	for _, dbPlaylistTrack := range dbPlaylistTracks {
+		if spotifyTrackMap[dbPlaylistTrack.TrackID.String] {
+			continue
+		}
+
+		log.Info().Str("spotify_playlist_id", dbPlaylist).Str("spotify_track_id", dbPlaylistTrack.TrackID.String).Msg("removing track from database")
+		err := s.Queries.DeletePlaylistTrack(ctx, db.DeletePlaylistTrackParams{
+			PlaylistID: dbPlaylistTrack.PlaylistID,
+			TrackID:    dbPlaylistTrack.TrackID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// fetch the live tidal playlist as it may have changed during the sync
+	tidalTracks, err := s.TidalService.GetPlaylistTracks(ctx, tidalPlaylistID)
+	if err != nil {
+		return err
+	}
+
+	removals, err := s.mirrorRemovals(ctx, tidalTracks.Items, spotifyTracks, nil)
+	if err != nil {
+		return err
+	}
+
+	if len(removals) == 0 {
+		return nil
+	}
+
+	removeIndices := make([]int64, 0, len(removals))
+	for _, removal := range removals {
+		log.Info().Str("tidal_playlist_id", tidalPlaylistID).Str("tidal_track_id", removal.TidalTrackID).Str("tidal_track_name", removal.Name).Int64("index", removal.Index).Msg("removing track from tidal playlist")
+		removeIndices = append(removeIndices, removal.Index)
+	}
+
+	if err := s.removeTidalPlaylistItems(ctx, tidalPlaylistID, removeIndices); err != nil {
+		return err
+	}
+
+	log.Info().Str("tidal_playlist_id", tidalPlaylistID).Msgf("removed %d tracks from tidal playlist", len(removeIndices))
+
+	return nil
+}
+
+// mirrorRemovals returns the items of the Tidal playlist that do not belong to a track of the Spotify playlist.
+// Tracks are kept if they were matched to a Spotify track or share an ISRC with one.
+// plannedMatches are the Tidal IDs of tracks matched during a dry run keyed by Spotify ID, as they are not saved to the database.
+func (s *Service) mirrorRemovals(ctx context.Context, tidalTracks []tidal.Track, spotifyTracks []*libSpotify.FullTrack, plannedMatches map[string]string) ([]PlannedRemoval, error) {
+	// build the set of tracks that should be in the tidal playlist
+	spotifyIsrcMap := make(map[string]bool)
+	expectedTidalTrackMap := make(map[string]bool)
+	for _, spotifyTrack := range spotifyTracks {
+		if spotifyTrack == nil {
+			continue
+		}
+		if isrc := spotifyTrack.ExternalIDs["isrc"]; isrc != "" {
+			spotifyIsrcMap[isrc] = true
+		}
+
+		if tidalID, ok := plannedMatches[spotifyTrack.ID.String()]; ok {
+			expectedTidalTrackMap[tidalID] = true
+			continue
+		}
+
+		mapping, err := s.Queries.GetTrackMapping(ctx, spotifyTrack.ID.String())
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		expectedTidalTrackMap[mapping.TidalID] = true
+	}
+
+	var removals []PlannedRemoval
+	for i, tidalTrack := range tidalTracks {
+		if expectedTidalTrackMap[strconv.FormatInt(tidalTrack.ID, 10)] || spotifyIsrcMap[tidalTrack.Isrc] {
+			continue
+		}
+
+		// prefer the item index reported by tidal, falling back to the position in the playlist
+		index := int64(i)
+		if tidalTrack.ItemUUID != "" {
+			index = tidalTrack.Index
+		}
+
+		removals = append(removals, PlannedRemoval{
+			TidalTrackID: strconv.FormatInt(tidalTrack.ID, 10),
+			Name:         tidalTrack.Title,
+			Index:        index,
+		})
+	}
+
+	return removals, nil
+}
+
+// removeTidalPlaylistItems removes the items at the provided indices from the Tidal playlist in chunks.
+func (s *Service) removeTidalPlaylistItems(ctx context.Context, tidalPlaylistID string, indices []int64) error {
+	// remove from the end of the playlist first so earlier indices remain valid between requests
+	sort.Slice(indices, func(i, j int) bool { return indices[i] > indices[j] })
+	for start := 0; start < len(indices); start += mirrorRemoveChunkSize {
+		end := start + mirrorRemoveChunkSize
+		if end > len(indices) {
+			end = len(indices)
+		}
+		if err := s.TidalService.RemoveTracksFromPlaylist(ctx, tidalPlaylistID, indices[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
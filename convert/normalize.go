@@ -0,0 +1,64 @@
+package convert
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// apostrophes are dropped so "don't", "don’t" and "dont" compare equal
+var punctuationReplacer = strings.NewReplacer(
+	"'", "",
+	"‘", "", // left single quote
+	"’", "", // right single quote
+	"‛", "", // single high-reversed-9 quote
+	"′", "", // prime
+	"`", "",
+	"&", " and ",
+)
+
+// featured artist markers, e.g. "feat.", "ft.", "featuring"
+var featuringRegexp = regexp.MustCompile(`\b(feat|ft|featuring)\b\.?`)
+
+// diacriticRemover decomposes characters and removes combining marks, e.g. "é" becomes "e" and full-width "Ａ" becomes "A".
+var diacriticRemover = transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// normalize returns a canonical form of a title or name for comparison.
+// Characters are folded to their compatibility form without diacritics and lower cased, apostrophes are removed,
+// "&" becomes "and", featured artist markers become "feat", and remaining punctuation is replaced with spaces.
+// For example "Beyoncé" becomes "beyonce", "Don’t Stop" becomes "dont stop", and "Ｊａｙ-Ｚ" becomes "jay z".
+func normalize(s string) string {
+	folded, _, err := transform.String(diacriticRemover, s)
+	if err == nil {
+		s = folded
+	}
+
+	s = punctuationReplacer.Replace(s)
+	s = strings.ToLower(s)
+	s = featuringRegexp.ReplaceAllString(s, "feat")
+
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			return r
+		}
+		return ' '
+	}, s)
+
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// versionSeparatorRegexp matches the start of a version or featured artist suffix, e.g. " - Live", " (Remix)", " [Bonus Track]", " feat. Artist".
+// Hyphens without surrounding spaces are part of the name, e.g. "Jay-Z" or "Re-Wired".
+var versionSeparatorRegexp = regexp.MustCompile(`(?i)\s+[-\x{2013}\x{2014}]\s+|\s*[(\[]|\s+(feat|ft|featuring)\.?\s`)
+
+// cleanName removes version and featured artist suffixes from a track or album name.
+func cleanName(name string) string {
+	if loc := versionSeparatorRegexp.FindStringIndex(name); loc != nil && loc[0] > 0 {
+		name = name[:loc[0]]
+	}
+	return strings.TrimSpace(name)
+}
@@ -0,0 +1,84 @@
+package convert
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"diacritics", "Beyoncé", "beyonce"},
+		{"without diacritics", "Beyonce", "beyonce"},
+		{"slash", "AC/DC", "ac dc"},
+		{"curly apostrophe", "Don’t Stop Believin’", "dont stop believin"},
+		{"straight apostrophe", "Don't Stop Believin'", "dont stop believin"},
+		{"full-width", "Ｊａｙ-Ｚ", "jay z"},
+		{"hyphen", "Jay-Z", "jay z"},
+		{"ampersand", "Simon & Garfunkel", "simon and garfunkel"},
+		{"and", "Simon and Garfunkel", "simon and garfunkel"},
+		{"feat", "Umbrella (feat. JAY-Z)", "umbrella feat jay z"},
+		{"ft", "Umbrella (ft. JAY-Z)", "umbrella feat jay z"},
+		{"featuring", "Umbrella featuring JAY-Z", "umbrella feat jay z"},
+		{"feat in a word", "Defeated Left", "defeated left"},
+		{"whitespace", "  Hello   World  ", "hello world"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalize(tt.in); got != tt.want {
+				t.Errorf("normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"Beyoncé", "Beyonce"},
+		{"Don’t", "Don't"},
+		{"Don't", "Dont"},
+		{"ＡＣ／ＤＣ", "AC/DC"},
+		{"Rock & Roll", "Rock and Roll"},
+		{"Song feat. Artist", "Song ft. Artist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a, func(t *testing.T) {
+			if normalize(tt.a) != normalize(tt.b) {
+				t.Errorf("normalize(%q) = %q, normalize(%q) = %q, want equal", tt.a, normalize(tt.a), tt.b, normalize(tt.b))
+			}
+		})
+	}
+}
+
+func TestCleanName(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "Thunderstruck", "Thunderstruck"},
+		{"hyphenated artist", "Jay-Z", "Jay-Z"},
+		{"hyphenated title", "Re-Wired", "Re-Wired"},
+		{"hyphenated title with version", "Re-Wired - Remastered 2020", "Re-Wired"},
+		{"spaced hyphen", "Bohemian Rhapsody - Remastered 2011", "Bohemian Rhapsody"},
+		{"en dash", "Hallelujah – Live", "Hallelujah"},
+		{"parentheses", "Crazy in Love (feat. Jay-Z)", "Crazy in Love"},
+		{"brackets", "Highway to Hell [Bonus Track]", "Highway to Hell"},
+		{"feat", "Empire State of Mind feat. Alicia Keys", "Empire State of Mind"},
+		{"ft", "Empire State of Mind ft. Alicia Keys", "Empire State of Mind"},
+		{"leading parenthesis", "(I Can't Get No) Satisfaction", "(I Can't Get No) Satisfaction"},
+		{"full-width", "Ｊａｙ-Ｚ (Live)", "Ｊａｙ-Ｚ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanName(tt.in); got != tt.want {
+				t.Errorf("cleanName(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package convert
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+
+	"github.com/zibbp/spotify-playlist-sync/tidal"
+	libSpotify "github.com/zmb3/spotify/v2"
+
+	"github.com/rs/zerolog/log"
+)
+
+// orderPlaylist moves tracks in the Tidal playlist so they match the order of the Spotify playlist.
+// Tidal tracks that do not belong to a Spotify track are moved to the end of the playlist, keeping their relative order.
+func (s *Service) orderPlaylist(ctx context.Context, tidalPlaylistID string, spotifyTracks []*libSpotify.FullTrack) error {
+	tidalTracks, err := s.TidalService.GetPlaylistTracks(ctx, tidalPlaylistID)
+	if err != nil {
+		return err
+	}
+
+	moves, err := s.orderMoves(ctx, tidalTracks.Items, spotifyTracks, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, move := range moves {
+		log.Debug().Str("tidal_playlist_id", tidalPlaylistID).Str("tidal_track_id", move.TidalTrackID).Int("index", move.From).Int("to_index", move.To).Msg("moving track in tidal playlist")
+		if err := s.TidalService.MoveTrackInPlaylist(ctx, tidalPlaylistID, int64(move.From), int64(move.To)); err != nil {
+			return err
+		}
+	}
+
+	if len(moves) > 0 {
+		log.Info().Str("tidal_playlist_id", tidalPlaylistID).Msgf("moved %d tracks to match spotify playlist order", len(moves))
+	}
+
+	return nil
+}
+
+// orderMoves returns the moves that put the Tidal playlist items in the order of the Spotify playlist.
+// Each move is relative to the playlist after the previous moves were applied.
+// plannedMatches are the Tidal IDs of tracks matched during a dry run keyed by Spotify ID, as they are not saved to the database.
+func (s *Service) orderMoves(ctx context.Context, tidalTracks []tidal.Track, spotifyTracks []*libSpotify.FullTrack, plannedMatches map[string]string) ([]PlannedMove, error) {
+	// position of each track in the spotify playlist keyed by tidal id and isrc
+	tidalTrackPositions := make(map[string]int)
+	isrcPositions := make(map[string]int)
+	for i, spotifyTrack := range spotifyTracks {
+		if spotifyTrack == nil {
+			continue
+		}
+		if isrc := spotifyTrack.ExternalIDs["isrc"]; isrc != "" {
+			if _, ok := isrcPositions[isrc]; !ok {
+				isrcPositions[isrc] = i
+			}
+		}
+
+		tidalID, ok := plannedMatches[spotifyTrack.ID.String()]
+		if !ok {
+			mapping, err := s.Queries.GetTrackMapping(ctx, spotifyTrack.ID.String())
+			if err == sql.ErrNoRows {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			tidalID = mapping.TidalID
+		}
+		if _, ok := tidalTrackPositions[tidalID]; !ok {
+			tidalTrackPositions[tidalID] = i
+		}
+	}
+
+	// current order of the tidal playlist and the position each item should have
+	current := make([]int64, len(tidalTracks))
+	names := make(map[int64]string)
+	positions := make(map[int64]int)
+	for i, tidalTrack := range tidalTracks {
+		current[i] = tidalTrack.ID
+		names[tidalTrack.ID] = tidalTrack.Title
+
+		position, ok := tidalTrackPositions[strconv.FormatInt(tidalTrack.ID, 10)]
+		if !ok {
+			position, ok = isrcPositions[tidalTrack.Isrc]
+		}
+		if !ok {
+			position = len(spotifyTracks) + i
+		}
+		positions[tidalTrack.ID] = position
+	}
+
+	desired := make([]int64, len(current))
+	copy(desired, current)
+	sort.SliceStable(desired, func(i, j int) bool { return positions[desired[i]] < positions[desired[j]] })
+
+	// move each out of place item into position, tracking the playlist locally
+	var moves []PlannedMove
+	for target := range desired {
+		if current[target] == desired[target] {
+			continue
+		}
+
+		index := target + 1
+		for index < len(current) && current[index] != desired[target] {
+			index++
+		}
+		if index == len(current) {
+			continue
+		}
+
+		moves = append(moves, PlannedMove{
+			TidalTrackID: strconv.FormatInt(desired[target], 10),
+			Name:         names[desired[target]],
+			From:         index,
+			To:           target,
+		})
+
+		moved := current[index]
+		copy(current[target+1:index+1], current[target:index])
+		current[target] = moved
+	}
+
+	return moves, nil
+}
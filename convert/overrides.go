@@ -0,0 +1,289 @@
+package convert
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/zibbp/spotify-playlist-sync/db"
+	"github.com/zibbp/spotify-playlist-sync/tidal"
+	spotifyPkg "github.com/zmb3/spotify/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/rs/zerolog/log"
+)
+
+// errTrackIgnored is returned for Spotify tracks that are ignored by an override and should not be synced.
+var errTrackIgnored = errors.New("track is ignored by an override")
+
+// TrackOverride pins a Spotify track to a Tidal track, or ignores it when syncing.
+type TrackOverride struct {
+	SpotifyID string `json:"spotify_id" yaml:"spotify_id"`
+	TidalID   string `json:"tidal_id,omitempty" yaml:"tidal_id,omitempty"`
+	Ignore    bool   `json:"ignore,omitempty" yaml:"ignore,omitempty"`
+}
+
+// TrackOverridesFile is the format of an overrides file shared with import and export.
+type TrackOverridesFile struct {
+	Overrides []TrackOverride `json:"overrides" yaml:"overrides"`
+}
+
+// trackOverride returns the pinned match for the Spotify track if one exists.
+// errTrackIgnored is returned if the track is ignored.
+func (s *Service) trackOverride(ctx context.Context, spotifyTrack *spotifyPkg.FullTrack) (*TrackMatch, error) {
+	override, err := s.Queries.GetTrackOverride(ctx, spotifyTrack.ID.String())
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if override.Ignored {
+		return nil, errTrackIgnored
+	}
+
+	return &TrackMatch{
+		SpotifyID:  override.SpotifyID,
+		Isrc:       spotifyTrack.ExternalIDs["isrc"],
+		TidalID:    override.TidalID.String,
+		Method:     MatchMethodManual,
+		Confidence: 1,
+	}, nil
+}
+
+// tidalTrackOverride returns the pinned match of the Tidal track if a Spotify track is pinned to it.
+func (s *Service) tidalTrackOverride(ctx context.Context, tidalTrack tidal.Track) (*TrackMatch, error) {
+	tidalID := strconv.FormatInt(tidalTrack.ID, 10)
+	override, err := s.Queries.GetTrackOverrideByTidalId(ctx, sql.NullString{String: tidalID, Valid: true})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &TrackMatch{
+		SpotifyID:  override.SpotifyID,
+		Isrc:       tidalTrack.Isrc,
+		TidalID:    tidalID,
+		Method:     MatchMethodManual,
+		Confidence: 1,
+	}, nil
+}
+
+// checkTrackIgnored returns errTrackIgnored if the Spotify track is ignored by an override.
+func (s *Service) checkTrackIgnored(ctx context.Context, spotifyID string) error {
+	override, err := s.Queries.GetTrackOverride(ctx, spotifyID)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if override.Ignored {
+		return errTrackIgnored
+	}
+	return nil
+}
+
+// SetTrackOverride pins the Spotify track to the Tidal track.
+// The track mapping is replaced so mirror and preserve order use the pinned track as well.
+// Playlists that already contain the track are synced again so the pinned track is added.
+func SetTrackOverride(ctx context.Context, queries *db.Queries, spotifyID string, tidalID string) error {
+	if spotifyID == "" || tidalID == "" {
+		return fmt.Errorf("spotify track id and tidal track id are required")
+	}
+
+	err := queries.UpsertTrackOverride(ctx, db.UpsertTrackOverrideParams{
+		SpotifyID: spotifyID,
+		TidalID:   sql.NullString{String: tidalID, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = queries.UpsertTrackMapping(ctx, db.UpsertTrackMappingParams{
+		SpotifyID:   spotifyID,
+		TidalID:     tidalID,
+		MatchMethod: MatchMethodManual,
+		Confidence:  1,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := queries.DeleteMissingTrack(ctx, spotifyID); err != nil {
+		return err
+	}
+
+	return resyncTrack(ctx, queries, spotifyID)
+}
+
+// IgnoreTrack stops the Spotify track from being synced or reported as missing.
+func IgnoreTrack(ctx context.Context, queries *db.Queries, spotifyID string) error {
+	if spotifyID == "" {
+		return fmt.Errorf("spotify track id is required")
+	}
+
+	err := queries.UpsertTrackOverride(ctx, db.UpsertTrackOverrideParams{
+		SpotifyID: spotifyID,
+		Ignored:   true,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := queries.DeleteTrackMapping(ctx, spotifyID); err != nil {
+		return err
+	}
+
+	if err := queries.DeleteMissingTrack(ctx, spotifyID); err != nil {
+		return err
+	}
+
+	return resyncTrack(ctx, queries, spotifyID)
+}
+
+// ClearTrackOverride removes the override for the Spotify track so it is matched automatically on the next sync.
+func ClearTrackOverride(ctx context.Context, queries *db.Queries, spotifyID string) error {
+	mapping, err := queries.GetTrackMapping(ctx, spotifyID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil && mapping.MatchMethod == MatchMethodManual {
+		if err := queries.DeleteTrackMapping(ctx, spotifyID); err != nil {
+			return err
+		}
+	}
+
+	if err := queries.DeleteTrackOverride(ctx, spotifyID); err != nil {
+		return err
+	}
+
+	return resyncTrack(ctx, queries, spotifyID)
+}
+
+// resyncTrack makes the next sync match the Spotify track again in every playlist, including playlists that are already synced.
+// The track is removed from the synced tracks of its playlists and their snapshots are cleared so the playlists are not skipped.
+// Playlists with missing tracks are resynced as well as the track may be one of them.
+// With mirror enabled the previously matched Tidal track is removed from the Tidal playlists.
+func resyncTrack(ctx context.Context, queries *db.Queries, spotifyID string) error {
+	trackID := sql.NullString{String: spotifyID, Valid: true}
+	if err := queries.ResetTrackPlaylistSnapshots(ctx, trackID); err != nil {
+		return err
+	}
+	return queries.DeleteTrackFromPlaylists(ctx, trackID)
+}
+
+// ClearTrackOverrides removes every override.
+func ClearTrackOverrides(ctx context.Context, queries *db.Queries) error {
+	overrides, err := queries.ListTrackOverrides(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, override := range overrides {
+		if err := ClearTrackOverride(ctx, queries, override.SpotifyID); err != nil {
+			return err
+		}
+	}
+
+	return queries.DeleteAllTrackOverrides(ctx)
+}
+
+// ListTrackOverrides returns every override ordered by Spotify track ID.
+func ListTrackOverrides(ctx context.Context, queries *db.Queries) ([]TrackOverride, error) {
+	dbOverrides, err := queries.ListTrackOverrides(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make([]TrackOverride, 0, len(dbOverrides))
+	for _, override := range dbOverrides {
+		overrides = append(overrides, TrackOverride{
+			SpotifyID: override.SpotifyID,
+			TidalID:   override.TidalID.String,
+			Ignore:    override.Ignored,
+		})
+	}
+
+	return overrides, nil
+}
+
+// ExportTrackOverrides writes every override to the provided path.
+// The file is written as JSON if the path ends in ".json", otherwise YAML.
+func ExportTrackOverrides(ctx context.Context, queries *db.Queries, path string) error {
+	overrides, err := ListTrackOverrides(ctx, queries)
+	if err != nil {
+		return err
+	}
+
+	file := TrackOverridesFile{Overrides: overrides}
+
+	var data []byte
+	if isJsonPath(path) {
+		data, err = json.MarshalIndent(file, "", "	")
+	} else {
+		data, err = yaml.Marshal(file)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ImportTrackOverrides reads overrides from the provided path, replacing existing overrides for the same Spotify tracks.
+// The file is read as JSON if the path ends in ".json", otherwise YAML. The number of imported overrides is returned.
+func ImportTrackOverrides(ctx context.Context, queries *db.Queries, path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var file TrackOverridesFile
+	if isJsonPath(path) {
+		err = json.Unmarshal(data, &file)
+	} else {
+		err = yaml.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse overrides file: %w", err)
+	}
+
+	// validate the whole file before changing anything
+	for i, override := range file.Overrides {
+		if override.SpotifyID == "" {
+			return 0, fmt.Errorf("override %d is missing a spotify_id", i+1)
+		}
+		if override.Ignore == (override.TidalID != "") {
+			return 0, fmt.Errorf("override for spotify track %s must set exactly one of tidal_id or ignore", override.SpotifyID)
+		}
+	}
+
+	for _, override := range file.Overrides {
+		if override.Ignore {
+			err = IgnoreTrack(ctx, queries, override.SpotifyID)
+		} else {
+			err = SetTrackOverride(ctx, queries, override.SpotifyID, override.TidalID)
+		}
+		if err != nil {
+			return 0, err
+		}
+		log.Debug().Str("spotify_track_id", override.SpotifyID).Str("tidal_track_id", override.TidalID).Bool("ignore", override.Ignore).Msg("imported track override")
+	}
+
+	return len(file.Overrides), nil
+}
+
+func isJsonPath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
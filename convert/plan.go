@@ -0,0 +1,194 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zibbp/spotify-playlist-sync/spotify"
+	libSpotify "github.com/zmb3/spotify/v2"
+)
+
+// Plan describes the changes a sync would make without applying them.
+type Plan struct {
+	Playlists []*PlaylistPlan `json:"playlists"`
+	// Favorites are the saved albums and followed artists added to the Tidal favorites
+	Favorites []*FavoritesPlan `json:"favorites,omitempty"`
+}
+
+// PlaylistPlan describes the changes to a single Tidal playlist.
+type PlaylistPlan struct {
+	// Job is the name of the sync job the playlist was planned by, empty when not running a sync file
+	Job               string          `json:"job,omitempty"`
+	SpotifyPlaylistID string          `json:"spotify_playlist_id"`
+	Name              string          `json:"name"`
+	TidalPlaylistID   string          `json:"tidal_playlist_id,omitempty"`
+	Create            bool            `json:"create"`
+	Update            *PlaylistUpdate `json:"update,omitempty"`
+	TracksToAdd       []PlannedTrack  `json:"tracks_to_add"`
+	UnresolvedTracks  []PlannedTrack  `json:"unresolved_tracks"`
+	// TracksToRemove are the Tidal playlist items removed by mirror
+	TracksToRemove []PlannedRemoval `json:"tracks_to_remove,omitempty"`
+	// Moves are the Tidal playlist items moved by preserve order, in the order they are applied
+	Moves []PlannedMove `json:"moves,omitempty"`
+	// Conflicts are the tracks that could not be merged when syncing in both directions
+	Conflicts []spotify.TrackConflict `json:"conflicts,omitempty"`
+}
+
+// FavoritesPlan describes the albums or artists that would be added to the Tidal favorites.
+type FavoritesPlan struct {
+	// Job is the name of the sync job the favorites were planned by, empty when not running a sync file
+	Job string `json:"job,omitempty"`
+	// Kind is "albums" or "artists"
+	Kind       string            `json:"kind"`
+	ToAdd      []PlannedFavorite `json:"to_add"`
+	Unresolved []PlannedFavorite `json:"unresolved"`
+}
+
+// PlannedFavorite is a Spotify album or artist and the Tidal album or artist it was matched to, if any.
+type PlannedFavorite struct {
+	SpotifyID string        `json:"spotify_id"`
+	Name      string        `json:"name"`
+	Artists   []string      `json:"artists,omitempty"`
+	Match     *LibraryMatch `json:"match,omitempty"`
+	// Reason is why an unresolved album or artist was not matched
+	Reason string `json:"reason,omitempty"`
+}
+
+func newPlannedAlbum(spotifyAlbum libSpotify.FullAlbum, match *LibraryMatch, reason string) PlannedFavorite {
+	plannedAlbum := PlannedFavorite{
+		SpotifyID: spotifyAlbum.ID.String(),
+		Name:      spotifyAlbum.Name,
+		Match:     match,
+		Reason:    reason,
+	}
+	for _, artist := range spotifyAlbum.Artists {
+		plannedAlbum.Artists = append(plannedAlbum.Artists, artist.Name)
+	}
+	return plannedAlbum
+}
+
+// PlaylistUpdate is the new title and description of a Tidal playlist.
+type PlaylistUpdate struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// PlannedTrack is a Spotify track and the Tidal track it was matched to, if any.
+type PlannedTrack struct {
+	SpotifyTrackID string      `json:"spotify_track_id"`
+	Name           string      `json:"name"`
+	Artists        []string    `json:"artists"`
+	Isrc           string      `json:"isrc,omitempty"`
+	Match          *TrackMatch `json:"match,omitempty"`
+	// Reason is why an unresolved track was not matched
+	Reason string `json:"reason,omitempty"`
+}
+
+// PlannedRemoval is a Tidal playlist item that would be removed.
+type PlannedRemoval struct {
+	TidalTrackID string `json:"tidal_track_id"`
+	Name         string `json:"name"`
+	Index        int64  `json:"index"`
+}
+
+// PlannedMove is a Tidal playlist item that would be moved from one index to another.
+type PlannedMove struct {
+	TidalTrackID string `json:"tidal_track_id"`
+	Name         string `json:"name"`
+	From         int    `json:"from"`
+	To           int    `json:"to"`
+}
+
+func newPlannedTrack(spotifyTrack *libSpotify.FullTrack, match *TrackMatch, reason string) PlannedTrack {
+	plannedTrack := PlannedTrack{
+		SpotifyTrackID: spotifyTrack.ID.String(),
+		Name:           spotifyTrack.Name,
+		Isrc:           spotifyTrack.ExternalIDs["isrc"],
+		Match:          match,
+		Reason:         reason,
+	}
+	for _, artist := range spotifyTrack.Artists {
+		plannedTrack.Artists = append(plannedTrack.Artists, artist.Name)
+	}
+	return plannedTrack
+}
+
+// Print writes a human readable summary of the plan.
+func (p *Plan) Print(w io.Writer) {
+	job := ""
+	for _, playlist := range p.Playlists {
+		if playlist.Job != job {
+			job = playlist.Job
+			fmt.Fprintf(w, "job %q\n", job)
+		}
+
+		switch {
+		case playlist.Create:
+			fmt.Fprintf(w, "create playlist %q (spotify %s)\n", playlist.Name, playlist.SpotifyPlaylistID)
+		case playlist.Update != nil:
+			fmt.Fprintf(w, "update playlist %q (tidal %s) - title: %q, description: %q\n", playlist.Name, playlist.TidalPlaylistID, playlist.Update.Title, playlist.Update.Description)
+		default:
+			fmt.Fprintf(w, "playlist %q (tidal %s)\n", playlist.Name, playlist.TidalPlaylistID)
+		}
+
+		for _, track := range playlist.TracksToAdd {
+			fmt.Fprintf(w, "  + %s - %s -> tidal %s (%s, %.2f)\n", track.Name, strings.Join(track.Artists, ", "), track.Match.TidalID, track.Match.Method, track.Match.Confidence)
+		}
+		for _, track := range playlist.UnresolvedTracks {
+			fmt.Fprintf(w, "  ? %s - %s (unresolved: %s)\n", track.Name, strings.Join(track.Artists, ", "), track.Reason)
+		}
+		for _, track := range playlist.TracksToRemove {
+			fmt.Fprintf(w, "  - %s (tidal %s)\n", track.Name, track.TidalTrackID)
+		}
+		for _, move := range playlist.Moves {
+			fmt.Fprintf(w, "  ~ %s (tidal %s) %d -> %d\n", move.Name, move.TidalTrackID, move.From, move.To)
+		}
+		for _, conflict := range playlist.Conflicts {
+			fmt.Fprintf(w, "  ! %s (conflict: %s)\n", conflict.Name, conflict.Reason)
+		}
+	}
+
+	p.printFavorites(w)
+}
+
+// printFavorites writes a human readable summary of the favorites that would be added.
+func (p *Plan) printFavorites(w io.Writer) {
+	for _, favorites := range p.Favorites {
+		if favorites.Job != "" {
+			fmt.Fprintf(w, "favorite %s (job %q)\n", favorites.Kind, favorites.Job)
+		} else {
+			fmt.Fprintf(w, "favorite %s\n", favorites.Kind)
+		}
+
+		for _, favorite := range favorites.ToAdd {
+			fmt.Fprintf(w, "  + %s -> tidal %s (%s, %.2f)\n", favoriteName(favorite), favorite.Match.TidalID, favorite.Match.Method, favorite.Match.Confidence)
+		}
+		for _, favorite := range favorites.Unresolved {
+			fmt.Fprintf(w, "  ? %s (unresolved: %s)\n", favoriteName(favorite), favorite.Reason)
+		}
+	}
+}
+
+// favoriteName returns the name of the album or artist, including the artists of an album.
+func favoriteName(favorite PlannedFavorite) string {
+	if len(favorite.Artists) == 0 {
+		return favorite.Name
+	}
+	return fmt.Sprintf("%s - %s", favorite.Name, strings.Join(favorite.Artists, ", "))
+}
+
+// WritePlan writes the plan to disk as JSON.
+func WritePlan(path string, plan *Plan) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	json, err := json.MarshalIndent(plan, "", "	")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, json, 0644)
+}
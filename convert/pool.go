@@ -0,0 +1,91 @@
+package convert
+
+import (
+	"context"
+	"sync"
+
+	libSpotify "github.com/zmb3/spotify/v2"
+
+	"github.com/rs/zerolog/log"
+)
+
+// matchResult is the outcome of matching a single Spotify track.
+type matchResult struct {
+	Match  *TrackMatch
+	Reason string
+	Err    error
+	// Skipped is set for tracks that were not matched because the sync is shutting down
+	Skipped bool
+}
+
+// trackMatcher matches a single Spotify track.
+type trackMatcher func(ctx context.Context, spotifyTrack *libSpotify.FullTrack) (*TrackMatch, string, error)
+
+// matchTracks matches the tracks with up to the provided number of concurrent workers.
+// Results are handled in the order of the tracks, each as soon as it and all earlier tracks are matched.
+// When shutting down no new tracks are matched, tracks that are being matched are finished and handled before returning.
+func matchTracks(ctx context.Context, spotifyTracks []*libSpotify.FullTrack, concurrency int, match trackMatcher, handle func(spotifyTrack *libSpotify.FullTrack, result matchResult) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]matchResult, len(spotifyTracks))
+	done := make([]chan struct{}, len(spotifyTracks))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// the current track is finished when shutting down
+				m, reason, err := match(context.WithoutCancel(ctx), spotifyTracks[i])
+				results[i] = matchResult{Match: m, Reason: reason, Err: err}
+				close(done[i])
+			}
+		}()
+	}
+
+	// stop is closed when results are no longer handled
+	stop := make(chan struct{})
+	defer wg.Wait()
+	defer close(stop)
+
+	go func() {
+		defer close(jobs)
+		for i := range spotifyTracks {
+			if ctx.Err() == nil {
+				select {
+				case jobs <- i:
+					continue
+				case <-ctx.Done():
+				case <-stop:
+				}
+			}
+
+			// tracks that were not dispatched are skipped
+			for j := i; j < len(spotifyTracks); j++ {
+				results[j].Skipped = true
+				close(done[j])
+			}
+			return
+		}
+	}()
+
+	for i, spotifyTrack := range spotifyTracks {
+		<-done[i]
+		if results[i].Skipped {
+			log.Warn().Msgf("sync interrupted, %d tracks were not matched", len(spotifyTracks)-i)
+			return nil
+		}
+		if err := handle(spotifyTrack, results[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package convert
+
+import (
+	"path"
+	"strings"
+
+	"github.com/zibbp/spotify-playlist-sync/config"
+	libSpotify "github.com/zmb3/spotify/v2"
+	"golang.org/x/exp/slices"
+)
+
+// playlistSelected reports whether the selector selects the Spotify playlist.
+func playlistSelected(selector config.PlaylistSelector, playlist libSpotify.SimplePlaylist) bool {
+	id := string(playlist.ID)
+
+	if slices.Contains(selector.Exclude.IDs, id) || matchesName(selector.Exclude.Names, playlist.Name) {
+		return false
+	}
+
+	if selector.Owner != "" && playlist.Owner.ID != selector.Owner {
+		return false
+	}
+
+	if len(selector.IDs) == 0 && len(selector.Names) == 0 {
+		return true
+	}
+
+	return slices.Contains(selector.IDs, id) || matchesName(selector.Names, playlist.Name)
+}
+
+// matchesName reports whether the name matches any of the glob patterns, ignoring case.
+func matchesName(patterns []string, name string) bool {
+	name = globName(name)
+	for _, pattern := range patterns {
+		// patterns are validated when the sync file is loaded
+		if ok, _ := path.Match(globName(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// globName lowercases the name and replaces slashes, which * would not match in path.Match, with a character playlist names do not contain.
+func globName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "/", "\x00")
+}
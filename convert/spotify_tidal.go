@@ -3,6 +3,7 @@ package convert
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -14,7 +15,6 @@ import (
 	"github.com/zibbp/spotify-playlist-sync/spotify"
 	"github.com/zibbp/spotify-playlist-sync/tidal"
 	libSpotify "github.com/zmb3/spotify/v2"
-	"golang.org/x/exp/slices"
 
 	"github.com/rs/zerolog/log"
 )
@@ -36,55 +36,131 @@ func Initialize(spotifyService *spotify.Service, tidalService *tidal.Service, co
 	return &s, nil
 }
 
+// SyncOptions controls how playlists are synced.
+type SyncOptions struct {
+	SaveMissingTracks     bool
+	SaveTidalPlaylist     bool
+	SaveNavidromePlaylist bool
+	// Playlists selects the Spotify playlists that are synced. All playlists are synced if the selector is empty.
+	Playlists config.PlaylistSelector
+	// RecheckMissingTracks searches for tracks that were previously not found regardless of the retry interval.
+	RecheckMissingTracks bool
+	// MissingTrackRetryInterval is how long a track that was not found is skipped before searching again.
+	MissingTrackRetryInterval time.Duration
+	// Mirror removes tracks from the Tidal playlist that were removed from the Spotify playlist.
+	Mirror bool
+	// PreserveOrder reorders the Tidal playlist to match the Spotify playlist order.
+	PreserveOrder bool
+	// Full syncs every playlist even if its Spotify snapshot is unchanged since the last sync.
+	Full bool
+	// DryRun matches tracks and reports the changes that would be made without modifying Tidal or the database.
+	DryRun bool
+	// MatchThreshold is the minimum score between 0 and 1 a search result needs to be accepted as a match.
+	MatchThreshold float64
+	// CountryCode is the country whose Tidal catalog is searched.
+	CountryCode string
+	// Bidirectional merges changes made on either side of a linked playlist instead of copying the Spotify playlist to Tidal.
+	Bidirectional bool
+	// LikedSongs syncs the Spotify Liked Songs to the Tidal favorites or a Tidal playlist. Liked Songs are not synced if empty.
+	LikedSongs string
+	// SavedAlbums adds the Spotify saved albums to the Tidal favorite albums.
+	SavedAlbums bool
+	// FollowedArtists adds the Spotify followed artists to the Tidal favorite artists.
+	FollowedArtists bool
+	// Concurrency is the number of tracks that are matched at the same time.
+	Concurrency int
+	// Resume continues the last interrupted sync, reusing the journaled results instead of searching for the tracks again.
+	Resume bool
+	// Job is the name of the sync job being run, empty when not running a sync file.
+	Job string
+}
+
 // SpotifyToTidal converts a user's Spotify playlists to Tidal playlists.
-func (s *Service) SpotifyToTidal(ctx context.Context, saveMissingTracks bool, saveTidalPlaylist bool, saveNavidromePlaylist bool, spotifyPlaylistIDs []string) error {
+// During a dry run the planned changes are returned instead of being made, otherwise the plan is nil.
+func (s *Service) SpotifyToTidal(ctx context.Context, opts SyncOptions) (*Plan, error) {
 	log.Info().Msg("Starting Spotify to Tidal conversion")
 
+	// playlists and favorites are changed in the catalog of the country the tracks were searched in
+	if opts.CountryCode != "" {
+		s.TidalService.CountryCode = opts.CountryCode
+	}
+
 	// get all playlists from Spotify
-	spotifyPlaylists, err := s.SpotifyService.GetUserPlaylists()
+	spotifyPlaylists, err := s.SpotifyService.GetUserPlaylists(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	log.Info().Msgf("fetched %d Spotify playlists", len(spotifyPlaylists))
 
-	tidalPlaylists, err := s.TidalService.GetUserPlaylists()
+	// liked songs are synced like any other playlist
+	if opts.LikedSongs == LikedSongsPlaylist {
+		spotifyPlaylists = append(spotifyPlaylists, likedSongsPlaylist())
+	}
+
+	tidalPlaylists, err := s.TidalService.GetUserPlaylists(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	log.Info().Msgf("fetched %d Tidal playlists", len(tidalPlaylists.Items))
 
+	// hold the planned changes during a dry run
+	plan := &Plan{}
+
+	// record the progress of the sync so it can be resumed if interrupted
+	journal, err := s.startSyncJournal(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	// compare playlists
 	for _, spotifyPlaylist := range spotifyPlaylists {
-		if len(spotifyPlaylistIDs) > 0 {
-			if !slices.Contains(spotifyPlaylistIDs, string(spotifyPlaylist.ID)) {
-				log.Debug().Str("spotify_playlist_id", string(spotifyPlaylist.ID)).Str("spotify_playlist_name", spotifyPlaylist.Name).Msg("skipping playlist")
-				continue
-			}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if spotifyPlaylist.ID != likedSongsPlaylistID && !playlistSelected(opts.Playlists, spotifyPlaylist) {
+			log.Debug().Str("spotify_playlist_id", string(spotifyPlaylist.ID)).Str("spotify_playlist_name", spotifyPlaylist.Name).Msg("skipping playlist")
+			continue
+		}
+
+		// playlists created by the tidal to spotify sync are copies of a tidal playlist
+		if syncedFromTidal(spotifyPlaylist, tidalPlaylists.Items) {
+			log.Debug().Str("spotify_playlist_id", string(spotifyPlaylist.ID)).Str("spotify_playlist_name", spotifyPlaylist.Name).Msg("playlist was synced from tidal, skipping")
+			continue
 		}
 
 		// check if spotify playlist is in local database
-		dbPlaylist, err := s.Queries.GetPlaylistById(ctx, string(spotifyPlaylist.ID))
-		if err == sql.ErrNoRows {
+		dbPlaylistRow, err := s.Queries.GetPlaylistById(ctx, string(spotifyPlaylist.ID))
+		if err == sql.ErrNoRows && opts.DryRun {
+			dbPlaylistRow = db.Playlist{ID: string(spotifyPlaylist.ID)}
+		} else if err == sql.ErrNoRows {
 			// create new playlist
-			_, err := s.Queries.CreatePlaylist(ctx, string(spotifyPlaylist.ID))
+			dbPlaylistRow, err = s.Queries.CreatePlaylist(ctx, string(spotifyPlaylist.ID))
 			if err != nil {
-				return err
+				return nil, err
 			}
 		} else if err != nil {
-			return err
+			return nil, err
 		}
 
+		dbPlaylist := dbPlaylistRow.ID
 		if dbPlaylist == "" {
 			log.Warn().Str("name", spotifyPlaylist.Name).Str("id", spotifyPlaylist.ID.String()).Msgf("playlist is empty, skipping")
 			continue
 		}
 
+		// skip playlists that have not changed since the last complete sync
+		if playlistUnchanged(dbPlaylistRow, spotifyPlaylist, opts) {
+			log.Info().Str("spotify_playlist_id", spotifyPlaylist.ID.String()).Str("spotify_playlist_name", spotifyPlaylist.Name).Str("snapshot_id", spotifyPlaylist.SnapshotID).Msg("playlist unchanged since last sync, skipping")
+			continue
+		}
+
 		// get all local database tracks
 		dbPlaylistTracks, err := s.Queries.GetPlaylistTracks(ctx, sql.NullString{String: dbPlaylist, Valid: true})
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// create map of tracks
@@ -93,6 +169,12 @@ func (s *Service) SpotifyToTidal(ctx context.Context, saveMissingTracks bool, sa
 			dbPlaylistTrackMap[dbPlaylistTrack.TrackID.String] = true
 		}
 
+		playlistPlan := &PlaylistPlan{
+			Job:               opts.Job,
+			SpotifyPlaylistID: spotifyPlaylist.ID.String(),
+			Name:              spotifyPlaylist.Name,
+		}
+
 		// check if spotify playlist id exists in tidal description
 		// if it does not exist, create a new playlist
 		found := false
@@ -105,7 +187,9 @@ func (s *Service) SpotifyToTidal(ctx context.Context, saveMissingTracks bool, sa
 			}
 		}
 
-		if !found {
+		if !found && opts.DryRun {
+			playlistPlan.Create = true
+		} else if !found {
 			// create new playlist
 			var playlistName string
 			if spotifyPlaylist.Name == "" {
@@ -114,21 +198,61 @@ func (s *Service) SpotifyToTidal(ctx context.Context, saveMissingTracks bool, sa
 				playlistName = spotifyPlaylist.Name
 			}
 			log.Info().Msgf("Creating playlist: %s - %s", spotifyPlaylist.Name, spotifyPlaylist.Description)
-			createdTidalPlaylist, err := s.TidalService.CreatePlaylist(playlistName, fmt.Sprintf("%s:%s", string(spotifyPlaylist.ID), spotifyPlaylist.Description))
+			createdTidalPlaylist, err := s.TidalService.CreatePlaylist(ctx, playlistName, fmt.Sprintf("%s:%s", string(spotifyPlaylist.ID), spotifyPlaylist.Description))
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			tidalPlaylist = *createdTidalPlaylist
 		}
 
 		// check if playlist needs to be updated
-		if tidalPlaylist.UUID != "" && (tidalPlaylist.Title != spotifyPlaylist.Name && spotifyPlaylist.Name != "") || tidalPlaylist.Description != fmt.Sprintf("%s:%s", string(spotifyPlaylist.ID), spotifyPlaylist.Description) {
+		playlistPlan.TidalPlaylistID = tidalPlaylist.UUID
+		if opts.DryRun {
+			if found && ((tidalPlaylist.Title != spotifyPlaylist.Name && spotifyPlaylist.Name != "") || tidalPlaylist.Description != fmt.Sprintf("%s:%s", string(spotifyPlaylist.ID), spotifyPlaylist.Description)) {
+				playlistPlan.Update = &PlaylistUpdate{
+					Title:       spotifyPlaylist.Name,
+					Description: fmt.Sprintf("%s:%s", string(spotifyPlaylist.ID), spotifyPlaylist.Description),
+				}
+			}
+		} else if tidalPlaylist.UUID != "" && (tidalPlaylist.Title != spotifyPlaylist.Name && spotifyPlaylist.Name != "") || tidalPlaylist.Description != fmt.Sprintf("%s:%s", string(spotifyPlaylist.ID), spotifyPlaylist.Description) {
 			log.Info().Msgf("Updating playlist: %s - %s", spotifyPlaylist.Name, spotifyPlaylist.Description)
-			err := s.TidalService.UpdatePlaylist(tidalPlaylist.UUID, spotifyPlaylist.Name, fmt.Sprintf("%s:%s", string(spotifyPlaylist.ID), spotifyPlaylist.Description))
+			err := s.TidalService.UpdatePlaylist(ctx, tidalPlaylist.UUID, spotifyPlaylist.Name, fmt.Sprintf("%s:%s", string(spotifyPlaylist.ID), spotifyPlaylist.Description))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// liked songs are not a playlist that can be modified on spotify
+		if opts.Bidirectional && spotifyPlaylist.ID != likedSongsPlaylistID {
+			result, err := s.reconcilePlaylist(ctx, dbPlaylist, spotifyPlaylist, tidalPlaylist.UUID, opts, playlistPlan)
+			if err != nil {
+				return nil, err
+			}
+
+			if opts.DryRun {
+				plan.Playlists = append(plan.Playlists, playlistPlan)
+				continue
+			}
+
+			err = s.writeMissingTracks(spotify.MissingTracks{
+				Playlist:  spotifyPlaylist,
+				Tracks:    result.MissingTracks,
+				Reasons:   result.MissingTrackReasons,
+				Conflicts: result.Conflicts,
+			}, opts)
 			if err != nil {
-				return err
+				return nil, err
 			}
+
+			if err := s.writePlaylistFiles(ctx, spotifyPlaylist, tidalPlaylist.UUID, opts); err != nil {
+				return nil, err
+			}
+
+			if err := s.recordPlaylistSnapshot(ctx, dbPlaylist, spotifyPlaylist, opts, len(result.MissingTracks)); err != nil {
+				return nil, err
+			}
+			continue
 		}
 
 		//
@@ -136,31 +260,29 @@ func (s *Service) SpotifyToTidal(ctx context.Context, saveMissingTracks bool, sa
 		//
 
 		// get all tracks from Spotify playlist
-		spotifyTracks, err := s.SpotifyService.GetPlaylistTracks(spotifyPlaylist.ID)
+		spotifyTracks, err := s.spotifyPlaylistTracks(ctx, spotifyPlaylist)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		log.Info().Str("platform", "spotify").Msgf("fetched %d tracks from playlist %s", len(spotifyTracks), spotifyPlaylist.Name)
 
 		// get all tracks from Tidal playlist
-		tidalTracks, err := s.TidalService.GetPlaylistTracks(tidalPlaylist.UUID)
-		if err != nil {
-			return err
-		}
-
-		log.Info().Str("platform", "tidal").Msgf("fetched %d tracks from playlist %s", len(tidalTracks.Items), tidalPlaylist.Title)
+		// playlists that would be created during a dry run do not exist yet
+		var tidalTracks []tidal.Track
+		if tidalPlaylist.UUID != "" {
+			tidalPlaylistTracks, err := s.TidalService.GetPlaylistTracks(ctx, tidalPlaylist.UUID)
+			if err != nil {
+				return nil, err
+			}
+			tidalTracks = tidalPlaylistTracks.Items
 
-		// get the isrc of all tidal tracks
-		tidalTrackISRCMap := make(map[string]bool)
-		for _, tidalTrack := range tidalTracks.Items {
-			tidalTrackISRCMap[tidalTrack.Isrc] = true
+			log.Info().Str("platform", "tidal").Msgf("fetched %d tracks from playlist %s", len(tidalTracks), tidalPlaylist.Title)
 		}
 
-		// hold missing tracks
-		var missingTracks []*libSpotify.FullTrack
-
-		// loop over each spotify track to convert
+		// queue the tracks that are not in the playlist yet
+		var queuedTracks []*libSpotify.FullTrack
+		queuedTrackMap := make(map[string]bool)
 		for _, spotifyTrack := range spotifyTracks {
 			if spotifyTrack == nil {
 				log.Debug().Msg("skipping empty track")
@@ -173,112 +295,331 @@ func (s *Service) SpotifyToTidal(ctx context.Context, saveMissingTracks bool, sa
 				continue
 			}
 
-			// attempt to find track
-			tidalTrack, err := s.spotifyToTidalTrack(ctx, spotifyTrack)
+			if _, ok := queuedTrackMap[spotifyTrack.ID.String()]; ok {
+				log.Debug().Str("spotify_track_id", spotifyTrack.ID.String()).Str("spotify_track_name", spotifyTrack.Name).Msgf("track is already queued for playlist")
+				continue
+			}
+
+			queuedTrackMap[spotifyTrack.ID.String()] = true
+			queuedTracks = append(queuedTracks, spotifyTrack)
+		}
+		journalItems, err := journal.enqueue(ctx, dbPlaylist, queuedTracks)
+		if err != nil {
+			return nil, err
+		}
+
+		// hold missing tracks and why they were not found
+		var missingTracks []*libSpotify.FullTrack
+		missingTrackReasons := make(map[string]string)
+
+		// set if any track failed to sync so the snapshot is not recorded
+		incomplete := false
+
+		// hold matched tracks until they are added in a batch
+		var pendingTracks []pendingTrack
+
+		// attempt to find the tracks concurrently, the results are handled in playlist order
+		err = matchTracks(ctx, queuedTracks, opts.Concurrency, func(ctx context.Context, spotifyTrack *libSpotify.FullTrack) (*TrackMatch, string, error) {
+			return s.journaledTrackMatch(ctx, journal, dbPlaylist, journalItems, spotifyTrack, opts)
+		}, func(spotifyTrack *libSpotify.FullTrack, result matchResult) error {
+			tidalTrack, reason, err := result.Match, result.Reason, result.Err
+			if errors.Is(err, errTrackIgnored) {
+				log.Debug().Str("spotify_track_id", spotifyTrack.ID.String()).Str("spotify_track_name", spotifyTrack.Name).Msg("track is ignored, skipping")
+				return nil
+			}
 			if err != nil {
 				log.Error().Err(err).Str("spotify_track_id", spotifyTrack.ID.String()).Str("spotify_track_name", spotifyTrack.Name).Str("spotify_track_isrc", spotifyTrack.ExternalIDs["isrc"]).Msgf("failed to find track on Tidal")
 				missingTracks = append(missingTracks, spotifyTrack)
-				continue
+				missingTrackReasons[spotifyTrack.ID.String()] = err.Error()
+				playlistPlan.UnresolvedTracks = append(playlistPlan.UnresolvedTracks, newPlannedTrack(spotifyTrack, nil, err.Error()))
+				return nil
 			}
 
 			if tidalTrack == nil {
 				missingTracks = append(missingTracks, spotifyTrack)
-				log.Warn().Str("spotify_track_id", spotifyTrack.ID.String()).Str("spotify_track_name", spotifyTrack.Name).Msgf("track not found")
-				continue
+				missingTrackReasons[spotifyTrack.ID.String()] = reason
+				playlistPlan.UnresolvedTracks = append(playlistPlan.UnresolvedTracks, newPlannedTrack(spotifyTrack, nil, reason))
+				log.Warn().Str("spotify_track_id", spotifyTrack.ID.String()).Str("spotify_track_name", spotifyTrack.Name).Str("reason", reason).Msgf("track not found")
+				return nil
 			}
 
-			// add track to playlist
-			log.Info().Str("spotify_track_id", spotifyTrack.ID.String()).Str("spotify_track_name", spotifyTrack.Name).Str("tidal_playlist_id", tidalPlaylist.UUID).Str("tidal_track_id", tidalTrack.Id).Msgf("adding track to tidal playlist")
-			err = s.TidalService.AddTrackToPlaylist(tidalPlaylist.UUID, tidalTrack.Id)
-			if err != nil {
-				log.Error().Str("spotify_track_id", spotifyTrack.ID.String()).Str("spotify_track_name", spotifyTrack.Name).Str("tidal_playlist_id", tidalPlaylist.UUID).Str("tidal_track_id", tidalTrack.Id).Msgf("error adding track to playlist")
-				continue
+			if opts.DryRun {
+				playlistPlan.TracksToAdd = append(playlistPlan.TracksToAdd, newPlannedTrack(spotifyTrack, tidalTrack, ""))
+				return nil
 			}
 
-			// add track to database
-			err = s.Queries.AddTrackToPlaylist(ctx, db.AddTrackToPlaylistParams{
-				PlaylistID: sql.NullString{String: dbPlaylist, Valid: true},
-				TrackID:    sql.NullString{String: spotifyTrack.ID.String(), Valid: true},
-			})
-			if err != nil {
-				log.Error().Str("spotify_track_id", spotifyTrack.ID.String()).Str("spotify_track_name", spotifyTrack.Name).Msgf("error adding track to database")
-				continue
+			// queue track to be added to playlist
+			log.Info().Str("spotify_track_id", spotifyTrack.ID.String()).Str("spotify_track_name", spotifyTrack.Name).Str("tidal_playlist_id", tidalPlaylist.UUID).Str("tidal_track_id", tidalTrack.TidalID).Msgf("queueing track for tidal playlist")
+			pendingTracks = append(pendingTracks, pendingTrack{SpotifyTrack: spotifyTrack, Match: tidalTrack})
+
+			// add the tracks once they fill a tidal request so progress is saved during long syncs
+			if len(pendingTracks) >= tidal.AddTracksChunkSize {
+				if err := s.addTracksToTidalPlaylist(context.WithoutCancel(ctx), dbPlaylist, tidalPlaylist.UUID, pendingTracks); err != nil {
+					log.Error().Err(err).Str("tidal_playlist_id", tidalPlaylist.UUID).Msgf("error adding tracks to playlist")
+					incomplete = true
+				} else if err := journal.recordAdded(context.WithoutCancel(ctx), dbPlaylist, pendingTracks); err != nil {
+					return err
+				}
+				pendingTracks = nil
 			}
 
-			// sleep to prevent rate limiting
-			time.Sleep(1 * time.Second)
+			return nil
+		})
+		if err != nil {
+			return nil, err
 		}
 
-		// write missing tracks to file
-		if saveMissingTracks && (len(missingTracks) > 0) {
-			log.Info().Str("spotify_playlist", spotifyPlaylist.Name).Msgf("processing complete - found %d missing tracks", len(missingTracks))
-			err := spotify.WriteMissingTracks(fmt.Sprintf("%s", spotifyPlaylist.ID), spotify.MissingTracks{
-				Playlist: spotifyPlaylist,
-				Tracks:   missingTracks,
-			}, *s.SpotifyService.EnvConfig)
-			if err != nil {
-				return err
+		if opts.DryRun {
+			if err := s.planPlaylistChanges(ctx, playlistPlan, tidalTracks, spotifyTracks, opts); err != nil {
+				return nil, err
 			}
+			plan.Playlists = append(plan.Playlists, playlistPlan)
+			continue
 		}
 
-		if saveTidalPlaylist {
-			// fetch fresh tidal playlist to save to disk
-			tPlaylist, err := s.TidalService.GetPlaylist(tidalPlaylist.UUID)
-			if err != nil {
-				return err
-			}
-			tPlaylistTracks, err := s.TidalService.GetPlaylistTracks(tidalPlaylist.UUID)
-			if err != nil {
-				return err
-			}
-			for _, tidalTrack := range tPlaylistTracks.Items {
-				tPlaylist.Tracks = append(tPlaylist.Tracks, tidalTrack)
-			}
-			err = tidal.WriteTidalPlaylist(fmt.Sprintf("%s", tPlaylist.UUID), tPlaylist, *s.SpotifyService.EnvConfig)
-			if err != nil {
-				return err
-			}
+		// add remaining tracks
+		// matched tracks are still added when shutting down so the progress is not lost
+		if err := s.addTracksToTidalPlaylist(context.WithoutCancel(ctx), dbPlaylist, tidalPlaylist.UUID, pendingTracks); err != nil {
+			log.Error().Err(err).Str("tidal_playlist_id", tidalPlaylist.UUID).Msgf("error adding tracks to playlist")
+			incomplete = true
+		} else if err := journal.recordAdded(context.WithoutCancel(ctx), dbPlaylist, pendingTracks); err != nil {
+			return nil, err
 		}
 
-		if saveNavidromePlaylist {
-			tPlaylist, err := s.TidalService.GetPlaylist(tidalPlaylist.UUID)
+		err = s.writeMissingTracks(spotify.MissingTracks{
+			Playlist: spotifyPlaylist,
+			Tracks:   missingTracks,
+			Reasons:  missingTrackReasons,
+		}, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if opts.Mirror {
+			err := s.mirrorPlaylist(ctx, dbPlaylist, tidalPlaylist.UUID, spotifyTracks)
 			if err != nil {
-				return err
+				return nil, err
 			}
+		}
 
-			tPlaylistTracks, err := s.TidalService.GetPlaylistTracks(tidalPlaylist.UUID)
+		if opts.PreserveOrder {
+			err := s.orderPlaylist(ctx, tidalPlaylist.UUID, spotifyTracks)
 			if err != nil {
-				return err
-			}
-			for _, tidalTrack := range tPlaylistTracks.Items {
-				tPlaylist.Tracks = append(tPlaylist.Tracks, tidalTrack)
+				return nil, err
 			}
+		}
 
-			navidromePlaylist := navidrome.Playlist{
-				SourceId:      spotifyPlaylist.ID.String(),
-				DestinationId: tidalPlaylist.UUID,
-				Name:          spotifyPlaylist.Name,
-				Description:   spotifyPlaylist.Description,
-			}
+		if err := s.writePlaylistFiles(ctx, spotifyPlaylist, tidalPlaylist.UUID, opts); err != nil {
+			return nil, err
+		}
 
-			for _, track := range tPlaylist.Tracks {
-				navidromePlaylist.Tracks = append(navidromePlaylist.Tracks, navidrome.Track{
-					ID:       strconv.FormatInt(track.ID, 10),
-					Title:    track.Title,
-					Album:    track.Album.Title,
-					Artist:   track.Artist.Name,
-					Duration: track.Duration,
-					ISRC:     track.Isrc,
-				})
+		// record the snapshot so unchanged playlists are skipped next time
+		if !incomplete {
+			if err := s.recordPlaylistSnapshot(ctx, dbPlaylist, spotifyPlaylist, opts, len(missingTracks)); err != nil {
+				return nil, err
 			}
+		}
 
-			err = navidrome.WriteNavidromePlaylist(fmt.Sprintf("%s", tPlaylist.UUID), navidromePlaylist, s.SpotifyService.EnvConfig)
-			if err != nil {
-				return err
+	}
+
+	if opts.LikedSongs == LikedSongsFavorites {
+		if err := s.likedTracksToFavorites(ctx, opts, plan); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.SavedAlbums {
+		if err := s.savedAlbumsToFavorites(ctx, opts, plan); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.FollowedArtists {
+		if err := s.followedArtistsToFavorites(ctx, opts, plan); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := journal.finish(ctx); err != nil {
+		return nil, err
+	}
+
+	if !opts.DryRun {
+		return nil, nil
+	}
+
+	return plan, nil
+}
+
+// writeMissingTracks writes the tracks that were not found and the conflicts of the playlist to disk if enabled.
+func (s *Service) writeMissingTracks(missing spotify.MissingTracks, opts SyncOptions) error {
+	if !opts.SaveMissingTracks || (len(missing.Tracks) == 0 && len(missing.Conflicts) == 0) {
+		return nil
+	}
+
+	log.Info().Str("spotify_playlist", missing.Playlist.Name).Msgf("processing complete - found %d missing tracks, %d conflicts", len(missing.Tracks), len(missing.Conflicts))
+	return spotify.WriteMissingTracks(missing.Playlist.ID.String(), missing, *s.SpotifyService.EnvConfig)
+}
+
+// planPlaylistChanges adds the tracks that mirror would remove and preserve order would move to the dry run plan.
+// The Tidal playlist is not modified, the changes are computed on the playlist as it would be after adding the planned tracks.
+func (s *Service) planPlaylistChanges(ctx context.Context, playlistPlan *PlaylistPlan, tidalTracks []tidal.Track, spotifyTracks []*libSpotify.FullTrack, opts SyncOptions) error {
+	if !opts.Mirror && !opts.PreserveOrder {
+		return nil
+	}
+
+	// tracks matched during a dry run are not saved to the database
+	plannedMatches := make(map[string]string)
+	for _, track := range playlistPlan.TracksToAdd {
+		plannedMatches[track.SpotifyTrackID] = track.Match.TidalID
+	}
+
+	// planned tracks are appended to the end of the playlist
+	for _, track := range playlistPlan.TracksToAdd {
+		tidalID, err := strconv.ParseInt(track.Match.TidalID, 10, 64)
+		if err != nil {
+			return err
+		}
+		tidalTracks = append(tidalTracks, tidal.Track{ID: tidalID, Title: track.Name, Isrc: track.Isrc})
+	}
+
+	if opts.Mirror {
+		removals, err := s.mirrorRemovals(ctx, tidalTracks, spotifyTracks, plannedMatches)
+		if err != nil {
+			return err
+		}
+		playlistPlan.TracksToRemove = removals
+
+		// order the playlist as it would be after the removals
+		removed := make(map[string]bool)
+		for _, removal := range removals {
+			removed[removal.TidalTrackID] = true
+		}
+		remaining := make([]tidal.Track, 0, len(tidalTracks))
+		for _, tidalTrack := range tidalTracks {
+			if !removed[strconv.FormatInt(tidalTrack.ID, 10)] {
+				remaining = append(remaining, tidalTrack)
 			}
 		}
+		tidalTracks = remaining
+	}
+
+	if opts.PreserveOrder {
+		moves, err := s.orderMoves(ctx, tidalTracks, spotifyTracks, plannedMatches)
+		if err != nil {
+			return err
+		}
+		playlistPlan.Moves = moves
+	}
+
+	return nil
+}
+
+// writePlaylistFiles saves the synced Tidal playlist to disk as Tidal and Navidrome playlists if enabled.
+func (s *Service) writePlaylistFiles(ctx context.Context, spotifyPlaylist libSpotify.SimplePlaylist, tidalPlaylistID string, opts SyncOptions) error {
+	if opts.SaveTidalPlaylist {
+		// fetch fresh tidal playlist to save to disk
+		tPlaylist, err := s.TidalService.GetPlaylist(ctx, tidalPlaylistID)
+		if err != nil {
+			return err
+		}
+		tPlaylistTracks, err := s.TidalService.GetPlaylistTracks(ctx, tidalPlaylistID)
+		if err != nil {
+			return err
+		}
+		for _, tidalTrack := range tPlaylistTracks.Items {
+			tPlaylist.Tracks = append(tPlaylist.Tracks, tidalTrack)
+		}
+		err = tidal.WriteTidalPlaylist(fmt.Sprintf("%s", tPlaylist.UUID), tPlaylist, *s.SpotifyService.EnvConfig)
+		if err != nil {
+			return err
+		}
+	}
 
+	if opts.SaveNavidromePlaylist {
+		tPlaylist, err := s.TidalService.GetPlaylist(ctx, tidalPlaylistID)
+		if err != nil {
+			return err
+		}
+
+		tPlaylistTracks, err := s.TidalService.GetPlaylistTracks(ctx, tidalPlaylistID)
+		if err != nil {
+			return err
+		}
+		for _, tidalTrack := range tPlaylistTracks.Items {
+			tPlaylist.Tracks = append(tPlaylist.Tracks, tidalTrack)
+		}
+
+		navidromePlaylist := navidrome.Playlist{
+			SourceId:      spotifyPlaylist.ID.String(),
+			DestinationId: tidalPlaylistID,
+			Name:          spotifyPlaylist.Name,
+			Description:   spotifyPlaylist.Description,
+		}
+
+		for _, track := range tPlaylist.Tracks {
+			navidromePlaylist.Tracks = append(navidromePlaylist.Tracks, navidrome.Track{
+				ID:       strconv.FormatInt(track.ID, 10),
+				Title:    track.Title,
+				Album:    track.Album.Title,
+				Artist:   track.Artist.Name,
+				Duration: track.Duration,
+				ISRC:     track.Isrc,
+			})
+		}
+
+		err = navidrome.WriteNavidromePlaylist(fmt.Sprintf("%s", tPlaylist.UUID), navidromePlaylist, s.SpotifyService.EnvConfig)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// recordPlaylistSnapshot records the snapshot of a completely synced playlist so it is skipped while unchanged.
+// Playlists with missing tracks are synced again once the retry interval elapses.
+func (s *Service) recordPlaylistSnapshot(ctx context.Context, dbPlaylist string, spotifyPlaylist libSpotify.SimplePlaylist, opts SyncOptions, missingTracks int) error {
+	return s.Queries.UpdatePlaylistSnapshot(ctx, db.UpdatePlaylistSnapshotParams{
+		SnapshotID:      sql.NullString{String: spotifyPlaylist.SnapshotID, Valid: spotifyPlaylist.SnapshotID != ""},
+		SnapshotOptions: sql.NullString{String: snapshotOptions(opts), Valid: true},
+		MissingTracks:   int64(missingTracks),
+		ID:              dbPlaylist,
+	})
+}
+
+// snapshotOptions returns the options that change the Tidal playlist beyond adding tracks.
+// Snapshots recorded with different options are synced again, e.g. after enabling --mirror.
+func snapshotOptions(opts SyncOptions) string {
+	var options []string
+	if opts.Mirror {
+		options = append(options, "mirror")
+	}
+	if opts.PreserveOrder {
+		options = append(options, "preserve_order")
+	}
+	return strings.Join(options, ",")
+}
+
+// playlistUnchanged reports whether the playlist can be skipped because it was completely synced at its current snapshot with the same options.
+// Playlists with missing tracks are synced again once the retry interval elapses, or when rechecking missing tracks.
+// The tidal side may have changed when syncing in both directions so those playlists are never skipped.
+func playlistUnchanged(dbPlaylist db.Playlist, spotifyPlaylist libSpotify.SimplePlaylist, opts SyncOptions) bool {
+	if opts.Full || opts.Bidirectional {
+		return false
+	}
+	if !dbPlaylist.SnapshotID.Valid || dbPlaylist.SnapshotID.String != spotifyPlaylist.SnapshotID {
+		return false
+	}
+	if !dbPlaylist.SnapshotOptions.Valid || dbPlaylist.SnapshotOptions.String != snapshotOptions(opts) {
+		return false
+	}
+	if dbPlaylist.MissingTracks > 0 && (opts.RecheckMissingTracks || !dbPlaylist.SyncedAt.Valid || time.Since(dbPlaylist.SyncedAt.Time) >= opts.MissingTrackRetryInterval) {
+		return false
+	}
+	return true
+}
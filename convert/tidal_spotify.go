@@ -0,0 +1,361 @@
+package convert
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/zibbp/spotify-playlist-sync/tidal"
+	libSpotify "github.com/zmb3/spotify/v2"
+	"golang.org/x/exp/slices"
+
+	"github.com/rs/zerolog/log"
+)
+
+// TidalToSpotifyOptions controls how Tidal playlists are synced to Spotify.
+type TidalToSpotifyOptions struct {
+	// TidalPlaylistIDs limits the sync to the provided playlists. All playlists are synced if empty.
+	TidalPlaylistIDs []string
+	// MatchThreshold is the minimum score between 0 and 1 a search result needs to be accepted as a match.
+	MatchThreshold float64
+	// DryRun matches tracks without saving the matches to the database.
+	DryRun bool
+	// SaveMissingTracks writes the Tidal tracks that were not found on Spotify to disk.
+	SaveMissingTracks bool
+}
+
+// TidalToSpotify converts a user's Tidal playlists to Spotify playlists.
+// Spotify playlists are linked to their Tidal playlist by the Tidal UUID in the description.
+func (s *Service) TidalToSpotify(ctx context.Context, opts TidalToSpotifyOptions) error {
+	log.Info().Msg("Starting Tidal to Spotify conversion")
+
+	tidalPlaylists, err := s.TidalService.GetUserPlaylists(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("fetched %d Tidal playlists", len(tidalPlaylists.Items))
+
+	spotifyPlaylists, err := s.SpotifyService.GetUserPlaylists(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("fetched %d Spotify playlists", len(spotifyPlaylists))
+
+	for _, tidalPlaylist := range tidalPlaylists.Items {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if len(opts.TidalPlaylistIDs) > 0 {
+			if !slices.Contains(opts.TidalPlaylistIDs, tidalPlaylist.UUID) {
+				log.Debug().Str("tidal_playlist_id", tidalPlaylist.UUID).Str("tidal_playlist_name", tidalPlaylist.Title).Msg("skipping playlist")
+				continue
+			}
+		}
+
+		// playlists created by the spotify to tidal sync are copies of a spotify playlist
+		if syncedFromSpotify(tidalPlaylist, spotifyPlaylists) {
+			log.Debug().Str("tidal_playlist_id", tidalPlaylist.UUID).Str("tidal_playlist_name", tidalPlaylist.Title).Msg("playlist was synced from spotify, skipping")
+			continue
+		}
+
+		playlistName := tidalPlaylist.Title
+		if playlistName == "" {
+			playlistName = "Untitled"
+		}
+		description := fmt.Sprintf("%s:%s", tidalPlaylist.UUID, tidalPlaylist.Description)
+
+		// check if tidal playlist uuid exists in a spotify description
+		// if it does not exist, create a new playlist
+		// the spotify web api returns descriptions html escaped
+		var spotifyPlaylistID libSpotify.ID
+		for _, spotifyPlaylist := range spotifyPlaylists {
+			if strings.Contains(spotifyPlaylist.Description, tidalPlaylist.UUID) {
+				spotifyPlaylistID = spotifyPlaylist.ID
+				if spotifyPlaylist.Name != playlistName || html.UnescapeString(spotifyPlaylist.Description) != description {
+					log.Info().Msgf("Updating playlist: %s - %s", tidalPlaylist.Title, tidalPlaylist.Description)
+					if err := s.SpotifyService.UpdatePlaylist(ctx, spotifyPlaylist.ID, playlistName, description); err != nil {
+						return err
+					}
+				}
+				break
+			}
+		}
+
+		if spotifyPlaylistID == "" {
+			log.Info().Msgf("Creating playlist: %s - %s", tidalPlaylist.Title, tidalPlaylist.Description)
+			createdSpotifyPlaylist, err := s.SpotifyService.CreatePlaylist(ctx, playlistName, description)
+			if err != nil {
+				return err
+			}
+			spotifyPlaylistID = createdSpotifyPlaylist.ID
+		}
+
+		// get all tracks from Spotify playlist
+		spotifyTracks, err := s.SpotifyService.GetPlaylistTracks(ctx, spotifyPlaylistID)
+		if err != nil {
+			return err
+		}
+
+		spotifyTrackMap := make(map[libSpotify.ID]bool)
+		for _, spotifyTrack := range spotifyTracks {
+			if spotifyTrack != nil {
+				spotifyTrackMap[spotifyTrack.ID] = true
+			}
+		}
+
+		// get all tracks from Tidal playlist
+		tidalTracks, err := s.TidalService.GetPlaylistTracks(ctx, tidalPlaylist.UUID)
+		if err != nil {
+			return err
+		}
+
+		log.Info().Str("platform", "tidal").Msgf("fetched %d tracks from playlist %s", len(tidalTracks.Items), tidalPlaylist.Title)
+
+		var trackIDs []libSpotify.ID
+		var missingTracks []tidal.Track
+		missingTrackReasons := make(map[string]string)
+		for _, tidalTrack := range tidalTracks.Items {
+			// stop before the next track when shutting down
+			if ctx.Err() != nil {
+				log.Warn().Str("tidal_playlist_name", tidalPlaylist.Title).Msg("sync interrupted, saving progress")
+				break
+			}
+
+			// the current track is finished when shutting down
+			match, reason, err := s.tidalToSpotifyTrack(context.WithoutCancel(ctx), tidalTrack, opts)
+			if errors.Is(err, errTrackIgnored) {
+				log.Debug().Int64("tidal_track_id", tidalTrack.ID).Str("tidal_track_name", tidalTrack.Title).Msg("track is ignored, skipping")
+				continue
+			}
+			if err != nil {
+				log.Error().Err(err).Int64("tidal_track_id", tidalTrack.ID).Str("tidal_track_name", tidalTrack.Title).Str("tidal_track_isrc", tidalTrack.Isrc).Msg("failed to find track on Spotify")
+				missingTracks = append(missingTracks, tidalTrack)
+				missingTrackReasons[strconv.FormatInt(tidalTrack.ID, 10)] = err.Error()
+				continue
+			}
+			if match == nil {
+				log.Warn().Int64("tidal_track_id", tidalTrack.ID).Str("tidal_track_name", tidalTrack.Title).Str("reason", reason).Msg("track not found")
+				missingTracks = append(missingTracks, tidalTrack)
+				missingTrackReasons[strconv.FormatInt(tidalTrack.ID, 10)] = reason
+				continue
+			}
+
+			spotifyID := libSpotify.ID(match.SpotifyID)
+			if spotifyTrackMap[spotifyID] {
+				continue
+			}
+			spotifyTrackMap[spotifyID] = true
+
+			log.Info().Int64("tidal_track_id", tidalTrack.ID).Str("tidal_track_name", tidalTrack.Title).Str("spotify_playlist_id", spotifyPlaylistID.String()).Str("spotify_track_id", match.SpotifyID).Msg("queueing track for spotify playlist")
+			trackIDs = append(trackIDs, spotifyID)
+		}
+
+		// matched tracks are still added when shutting down so the progress is not lost
+		if err := s.SpotifyService.AddTracksToPlaylist(context.WithoutCancel(ctx), spotifyPlaylistID, trackIDs); err != nil {
+			return err
+		}
+
+		log.Info().Str("tidal_playlist", tidalPlaylist.Title).Msgf("processing complete - added %d tracks, %d missing tracks", len(trackIDs), len(missingTracks))
+
+		if opts.SaveMissingTracks && len(missingTracks) > 0 {
+			err := tidal.WriteMissingTracks(tidalPlaylist.UUID, tidal.MissingTracks{
+				Playlist: tidalPlaylist,
+				Tracks:   missingTracks,
+				Reasons:  missingTrackReasons,
+			}, *s.SpotifyService.EnvConfig)
+			if err != nil {
+				return err
+			}
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// tidalToSpotifyTrack attempts to find the provided Tidal track on Spotify.
+// Spotify tracks pinned to the Tidal track by an override are used first. Previously matched tracks are served from the local database.
+// Otherwise tracks are checked by ISRC first, falling back to scoring title/album/artist search results.
+// errTrackIgnored is returned if the matched Spotify track is ignored by an override.
+// If the track is not found the reason is returned instead of a match.
+func (s *Service) tidalToSpotifyTrack(ctx context.Context, tidalTrack tidal.Track, opts TidalToSpotifyOptions) (*TrackMatch, string, error) {
+	tidalID := strconv.FormatInt(tidalTrack.ID, 10)
+
+	overrideMatch, err := s.tidalTrackOverride(ctx, tidalTrack)
+	if err != nil {
+		return nil, "", err
+	}
+	if overrideMatch != nil {
+		log.Debug().Str("tidal_track_id", tidalID).Str("spotify_track_id", overrideMatch.SpotifyID).Msg("track override found in database")
+		return overrideMatch, "", nil
+	}
+
+	mapping, err := s.Queries.GetTrackMappingByTidalId(ctx, tidalID)
+	if err == nil {
+		log.Debug().Str("tidal_track_id", tidalID).Str("spotify_track_id", mapping.SpotifyID).Msg("track mapping found in database")
+		if err := s.checkTrackIgnored(ctx, mapping.SpotifyID); err != nil {
+			return nil, "", err
+		}
+		return trackMatchFromMapping(mapping.SpotifyID, mapping), "", nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, "", err
+	}
+
+	match, reason, err := s.searchSpotifyTrack(ctx, tidalTrack, opts)
+	if err != nil || match == nil {
+		return nil, reason, err
+	}
+	if err := s.checkTrackIgnored(ctx, match.SpotifyID); err != nil {
+		return nil, "", err
+	}
+
+	// dry runs do not write to the database
+	if opts.DryRun {
+		return match, "", nil
+	}
+
+	// keep existing mappings of the spotify track, they are used by the spotify to tidal sync
+	_, err = s.Queries.GetTrackMapping(ctx, match.SpotifyID)
+	if err == sql.ErrNoRows {
+		if err := s.saveTrackMatch(ctx, match); err != nil {
+			return nil, "", err
+		}
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	return match, "", nil
+}
+
+// searchSpotifyTrack looks up the Tidal track using the Spotify search API.
+// If no match is found the reason from the last search is returned.
+func (s *Service) searchSpotifyTrack(ctx context.Context, tidalTrack tidal.Track, opts TidalToSpotifyOptions) (*TrackMatch, string, error) {
+	tidalID := strconv.FormatInt(tidalTrack.ID, 10)
+
+	if tidalTrack.Isrc != "" {
+		// attempt to find the track using the ISRC
+		spotifyTracks, err := s.SpotifyService.SearchTrackByISRC(ctx, tidalTrack.Isrc)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(spotifyTracks) > 0 {
+			return &TrackMatch{
+				SpotifyID:  spotifyTracks[0].ID.String(),
+				Isrc:       tidalTrack.Isrc,
+				TidalID:    tidalID,
+				Method:     MatchMethodISRC,
+				Confidence: 1,
+			}, "", nil
+		}
+		log.Warn().Str("platform", "spotify").Str("tidal_track_id", tidalID).Str("tidal_track_name", tidalTrack.Title).Str("tidal_track_isrc", tidalTrack.Isrc).Msg("track not found via isrc")
+	}
+
+	// attempt to find track by scoring search results on name, artists, album, and duration
+	tidalFields := tidalTrackFields(tidalTrack)
+	tidalName := cleanName(tidalTrack.Title)
+
+	queries := []searchQuery{
+		{query: fmt.Sprintf("track:%s album:%s", tidalName, tidalTrack.Album.Title), method: MatchMethodAlbumSearch},
+	}
+	if len(tidalFields.Artists) > 0 {
+		queries = append(queries, searchQuery{query: fmt.Sprintf("track:%s artist:%s", tidalName, tidalFields.Artists[0]), method: MatchMethodArtistSearch})
+	}
+
+	// candidates from every search are scored together, stopping once a search yields a match
+	var candidates []libSpotify.FullTrack
+	var scores []candidateScore
+	var reason string
+	candidateMethods := make(map[libSpotify.ID]string)
+	for _, q := range queries {
+		log.Debug().Str("platform", "spotify").Str("query", q.query).Msg("searching for track")
+
+		spotifySearch, err := s.SpotifyService.SearchTracks(ctx, q.query)
+		if err != nil {
+			return nil, "", err
+		}
+
+		for _, candidate := range spotifySearch {
+			if _, ok := candidateMethods[candidate.ID]; ok {
+				continue
+			}
+			candidateMethods[candidate.ID] = q.method
+
+			score, mismatch := scoreTrack(spotifyTrackFields(&candidate), tidalFields)
+			log.Debug().Str("tidal_track_name", tidalTrack.Title).Str("spotify_track_id", candidate.ID.String()).Str("spotify_track_name", candidate.Name).Float64("score", score).Str("version_mismatch", mismatch).Msg("scored candidate")
+
+			candidates = append(candidates, candidate)
+			scores = append(scores, candidateScore{ID: candidate.ID.String(), Score: score, VersionMismatch: mismatch})
+		}
+
+		var best int
+		best, reason = selectBest(scores, opts.MatchThreshold, "spotify")
+		if best >= 0 {
+			isrc := candidates[best].ExternalIDs["isrc"]
+			if isrc == "" {
+				isrc = tidalTrack.Isrc
+			}
+			return &TrackMatch{
+				SpotifyID:  candidates[best].ID.String(),
+				Isrc:       isrc,
+				TidalID:    tidalID,
+				Method:     candidateMethods[candidates[best].ID],
+				Confidence: scores[best].Score,
+			}, "", nil
+		}
+	}
+
+	log.Info().Str("tidal_track_id", tidalID).Str("tidal_track_name", tidalTrack.Title).Str("reason", reason).Msg("no spotify match found")
+
+	return nil, reason, nil
+}
+
+func tidalTrackFields(track tidal.Track) trackFields {
+	fields := trackFields{
+		Title:    track.Title,
+		Version:  stringValue(track.Version),
+		Album:    track.Album.Title,
+		Duration: float64(track.Duration),
+		Explicit: track.Explicit,
+	}
+	for _, artist := range track.Artists {
+		fields.Artists = append(fields.Artists, artist.Name)
+	}
+	if len(fields.Artists) == 0 && track.Artist.Name != "" {
+		fields.Artists = append(fields.Artists, track.Artist.Name)
+	}
+	return fields
+}
+
+// syncedFromSpotify returns true if the Tidal playlist was created from a Spotify playlist by SpotifyToTidal.
+func syncedFromSpotify(tidalPlaylist tidal.Playlist, spotifyPlaylists []libSpotify.SimplePlaylist) bool {
+	if strings.Contains(tidalPlaylist.Description, likedSongsPlaylistID) {
+		return true
+	}
+	for _, spotifyPlaylist := range spotifyPlaylists {
+		if spotifyPlaylist.ID != "" && strings.Contains(tidalPlaylist.Description, spotifyPlaylist.ID.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// syncedFromTidal returns true if the Spotify playlist was created from a Tidal playlist by TidalToSpotify.
+func syncedFromTidal(spotifyPlaylist libSpotify.SimplePlaylist, tidalPlaylists []tidal.Playlist) bool {
+	for _, tidalPlaylist := range tidalPlaylists {
+		if tidalPlaylist.UUID != "" && strings.Contains(spotifyPlaylist.Description, tidalPlaylist.UUID) {
+			return true
+		}
+	}
+	return false
+}
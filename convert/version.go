@@ -0,0 +1,164 @@
+package convert
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// versionKind is a qualifier that distinguishes a recording from the original studio version.
+type versionKind string
+
+const (
+	versionLive         versionKind = "live"
+	versionAcoustic     versionKind = "acoustic"
+	versionRemix        versionKind = "remix"
+	versionEdit         versionKind = "edit"
+	versionExtended     versionKind = "extended"
+	versionInstrumental versionKind = "instrumental"
+	versionAcapella     versionKind = "acapella"
+	versionKaraoke      versionKind = "karaoke"
+	versionSpedUp       versionKind = "sped up"
+	versionSlowed       versionKind = "slowed"
+	versionDemo         versionKind = "demo"
+	versionRemaster     versionKind = "remaster"
+	versionMono         versionKind = "mono"
+)
+
+// versionPattern maps normalized qualifier text to a version kind.
+type versionPattern struct {
+	kind    versionKind
+	pattern *regexp.Regexp
+	// exclude prevents a match, e.g. "original mix" is not a remix
+	exclude *regexp.Regexp
+}
+
+var versionPatterns = []versionPattern{
+	{kind: versionLive, pattern: regexp.MustCompile(`\blive\b`)},
+	{kind: versionAcoustic, pattern: regexp.MustCompile(`\b(acoustic|unplugged)\b`)},
+	{kind: versionRemix, pattern: regexp.MustCompile(`\b(remix|rmx|mix)\b`), exclude: regexp.MustCompile(`^original mix$`)},
+	{kind: versionEdit, pattern: regexp.MustCompile(`\bedit\b`)},
+	{kind: versionExtended, pattern: regexp.MustCompile(`\bextended\b`)},
+	{kind: versionInstrumental, pattern: regexp.MustCompile(`\binstrumental\b`)},
+	{kind: versionAcapella, pattern: regexp.MustCompile(`\b(acapella|a cappella|a capella)\b`)},
+	{kind: versionKaraoke, pattern: regexp.MustCompile(`\bkaraoke\b`)},
+	{kind: versionSpedUp, pattern: regexp.MustCompile(`\b(sped up|speed up|nightcore)\b`)},
+	{kind: versionSlowed, pattern: regexp.MustCompile(`\b(slowed|reverb)\b`)},
+	{kind: versionDemo, pattern: regexp.MustCompile(`\bdemo\b`)},
+	{kind: versionRemaster, pattern: regexp.MustCompile(`\b(remaster|remastered)\b`)},
+	{kind: versionMono, pattern: regexp.MustCompile(`\bmono\b`)},
+}
+
+// neutralVersions are compatible with the original recording, e.g. "Remastered 2011".
+var neutralVersions = map[versionKind]bool{
+	versionRemaster: true,
+	versionMono:     true,
+}
+
+// trackVersion is the set of version qualifiers parsed from a track title.
+type trackVersion struct {
+	Kinds []versionKind
+	// Qualifier is the raw version text, e.g. "Live at Wembley"
+	Qualifier string
+}
+
+// String returns the version kinds, or "original" if there are none.
+func (v trackVersion) String() string {
+	if len(v.Kinds) == 0 {
+		return "original"
+	}
+	kinds := make([]string, 0, len(v.Kinds))
+	for _, kind := range v.Kinds {
+		kinds = append(kinds, string(kind))
+	}
+	return strings.Join(kinds, ", ")
+}
+
+// distinct returns the version kinds that are not compatible with the original recording.
+func (v trackVersion) distinct() []versionKind {
+	var kinds []versionKind
+	for _, kind := range v.Kinds {
+		if !neutralVersions[kind] {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// versionSegmentRegexp splits a qualifier into its bracketed and hyphen separated parts, e.g. "feat. Artist) [Live".
+var versionSegmentRegexp = regexp.MustCompile(`\s+[-\x{2013}\x{2014}]\s+|[()\[\]]`)
+
+// featuringSegmentRegexp matches a featured artist part of a qualifier, e.g. "feat. DJ Live".
+var featuringSegmentRegexp = regexp.MustCompile(`(?i)^(feat|ft|featuring)\b`)
+
+// stripFeaturing removes the featured artist parts of a qualifier so artist names are not parsed as versions.
+func stripFeaturing(qualifier string) string {
+	var parts []string
+	for _, part := range versionSegmentRegexp.Split(qualifier, -1) {
+		part = strings.TrimSpace(part)
+		if part == "" || featuringSegmentRegexp.MatchString(part) {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " - ")
+}
+
+// parseVersion parses the version qualifiers from a title and an optional separate version, e.g. Tidal's version attribute.
+// The qualifier of a title is everything removed by cleanName, e.g. "Live at Wembley" in "Song - Live at Wembley", without featured artists.
+func parseVersion(title string, version string) trackVersion {
+	qualifiers := make([]string, 0, 2)
+	if qualifier := strings.TrimSpace(strings.TrimPrefix(title, cleanName(title))); qualifier != "" {
+		if qualifier = stripFeaturing(strings.Trim(qualifier, " -–—()[]")); qualifier != "" {
+			qualifiers = append(qualifiers, qualifier)
+		}
+	}
+	if version = strings.TrimSpace(version); version != "" {
+		qualifiers = append(qualifiers, version)
+	}
+
+	v := trackVersion{Qualifier: strings.Join(qualifiers, " / ")}
+	normalized := normalize(v.Qualifier)
+	for _, versionPattern := range versionPatterns {
+		if versionPattern.exclude != nil && versionPattern.exclude.MatchString(normalized) {
+			continue
+		}
+		if versionPattern.pattern.MatchString(normalized) {
+			v.Kinds = append(v.Kinds, versionPattern.kind)
+		}
+	}
+
+	return v
+}
+
+// versionMismatch returns a reason if the versions describe different recordings.
+// An empty string is returned if the versions are compatible, e.g. "Remastered 2011" and the original.
+func versionMismatch(spotifyVersion trackVersion, tidalVersion trackVersion) string {
+	spotifyKinds := spotifyVersion.distinct()
+	tidalKinds := tidalVersion.distinct()
+	sort.Slice(spotifyKinds, func(i, j int) bool { return spotifyKinds[i] < spotifyKinds[j] })
+	sort.Slice(tidalKinds, func(i, j int) bool { return tidalKinds[i] < tidalKinds[j] })
+
+	if len(spotifyKinds) == len(tidalKinds) {
+		equal := true
+		for i := range spotifyKinds {
+			if spotifyKinds[i] != tidalKinds[i] {
+				equal = false
+				break
+			}
+		}
+		if equal {
+			return ""
+		}
+	}
+
+	return fmt.Sprintf("version mismatch: spotify is %s, tidal is %s", versionDescription(spotifyVersion), versionDescription(tidalVersion))
+}
+
+func versionDescription(v trackVersion) string {
+	if v.Qualifier == "" {
+		return v.String()
+	}
+	return fmt.Sprintf("%s (%q)", v.String(), v.Qualifier)
+}
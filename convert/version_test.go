@@ -0,0 +1,71 @@
+package convert
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		title     string
+		version   string
+		want      []versionKind
+		qualifier string
+	}{
+		{"original", "Bohemian Rhapsody", "", nil, ""},
+		{"remaster", "Bohemian Rhapsody - Remastered 2011", "", []versionKind{versionRemaster}, "Remastered 2011"},
+		{"live", "Hallelujah - Live at Wembley", "", []versionKind{versionLive}, "Live at Wembley"},
+		{"tidal version", "Hallelujah", "Live", []versionKind{versionLive}, "Live"},
+		{"remix", "Titanium (David Guetta Remix)", "", []versionKind{versionRemix}, "David Guetta Remix"},
+		{"original mix", "Strobe (Original Mix)", "", nil, "Original Mix"},
+		{"feat", "Song (feat. DJ Live)", "", nil, ""},
+		{"feat without brackets", "Song feat. Reverb & Co", "", nil, ""},
+		{"ft", "Song ft. Live Wire", "", nil, ""},
+		{"feat and version", "Song (feat. DJ Live) [Acoustic]", "", []versionKind{versionAcoustic}, "Acoustic"},
+		{"feat and remix", "Song (feat. Artist) - Slowed", "", []versionKind{versionSlowed}, "Slowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseVersion(tt.title, tt.version)
+			if !reflect.DeepEqual(got.Kinds, tt.want) {
+				t.Errorf("parseVersion(%q, %q).Kinds = %v, want %v", tt.title, tt.version, got.Kinds, tt.want)
+			}
+			if got.Qualifier != tt.qualifier {
+				t.Errorf("parseVersion(%q, %q).Qualifier = %q, want %q", tt.title, tt.version, got.Qualifier, tt.qualifier)
+			}
+		})
+	}
+}
+
+func TestVersionMismatch(t *testing.T) {
+	tests := []struct {
+		name         string
+		spotifyTitle string
+		tidalTitle   string
+		tidalVersion string
+		mismatch     bool
+	}{
+		{"same original", "Bohemian Rhapsody", "Bohemian Rhapsody", "", false},
+		{"remaster and original", "Bohemian Rhapsody - Remastered 2011", "Bohemian Rhapsody", "", false},
+		{"original and remaster version", "Bohemian Rhapsody", "Bohemian Rhapsody", "Remastered", false},
+		{"live and studio", "Hallelujah - Live", "Hallelujah", "", true},
+		{"studio and live version", "Hallelujah", "Hallelujah", "Live", true},
+		{"same live", "Hallelujah - Live", "Hallelujah", "Live", false},
+		{"remix and original mix", "Strobe - Radio Remix", "Strobe (Original Mix)", "", true},
+		{"original mix and original", "Strobe (Original Mix)", "Strobe", "", false},
+		{"feat and original", "Song (feat. DJ Live)", "Song", "", false},
+		{"feat and different feat", "Song feat. Reverb & Co", "Song (feat. Someone)", "", false},
+		{"feat live and studio", "Song (feat. Artist) - Live", "Song (feat. Artist)", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := versionMismatch(parseVersion(tt.spotifyTitle, ""), parseVersion(tt.tidalTitle, tt.tidalVersion))
+			if (reason != "") != tt.mismatch {
+				t.Errorf("versionMismatch(%q, %q %q) = %q, want mismatch %v", tt.spotifyTitle, tt.tidalTitle, tt.tidalVersion, reason, tt.mismatch)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// columnMigration adds a column to a table created by an earlier version of the schema.
+type columnMigration struct {
+	Table      string
+	Column     string
+	Definition string
+}
+
+// columnMigrations must be kept in sync with schema.sql.
+var columnMigrations = []columnMigration{
+	{Table: "playlists", Column: "snapshot_id", Definition: "TEXT"},
+	{Table: "playlists", Column: "snapshot_options", Definition: "TEXT"},
+	{Table: "playlists", Column: "missing_tracks", Definition: "INTEGER NOT NULL DEFAULT 0"},
+	{Table: "playlists", Column: "synced_at", Definition: "TIMESTAMP"},
+	{Table: "sync_runs", Column: "job", Definition: "TEXT NOT NULL DEFAULT ''"},
+}
+
+// Migrate adds columns that are missing from existing databases.
+// New tables are created by schema.sql, but CREATE TABLE IF NOT EXISTS does not alter existing tables.
+func Migrate(ctx context.Context, conn *sql.DB) error {
+	for _, m := range columnMigrations {
+		var count int
+		row := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", m.Table, m.Column)
+		if err := row.Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.Table, m.Column, m.Definition)); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", m.Table, m.Column, err)
+		}
+	}
+
+	return nil
+}
@@ -8,8 +8,34 @@ import (
 	"database/sql"
 )
 
+type LibraryMapping struct {
+	Kind        string
+	SpotifyID   string
+	TidalID     string
+	MatchMethod string
+	Confidence  float64
+	MatchedAt   sql.NullTime
+}
+
+type MissingTrack struct {
+	SpotifyID     string
+	Isrc          sql.NullString
+	Attempts      int64
+	LastCheckedAt sql.NullTime
+}
+
 type Playlist struct {
-	ID string
+	ID              string
+	SnapshotID      sql.NullString
+	SnapshotOptions sql.NullString
+	MissingTracks   int64
+	SyncedAt        sql.NullTime
+}
+
+type PlaylistBaseTrack struct {
+	PlaylistID string
+	SpotifyID  string
+	TidalID    string
 }
 
 type PlaylistTrack struct {
@@ -18,6 +44,41 @@ type PlaylistTrack struct {
 	AddedAt    sql.NullTime
 }
 
+type SyncRun struct {
+	ID         int64
+	Status     string
+	StartedAt  sql.NullTime
+	FinishedAt sql.NullTime
+	Job        string
+}
+
+type SyncRunItem struct {
+	RunID      int64
+	PlaylistID string
+	SpotifyID  string
+	Position   int64
+	Status     string
+	TidalID    sql.NullString
+	Reason     sql.NullString
+	UpdatedAt  sql.NullTime
+}
+
 type Track struct {
 	ID string
 }
+
+type TrackMapping struct {
+	SpotifyID   string
+	Isrc        sql.NullString
+	TidalID     string
+	MatchMethod string
+	Confidence  float64
+	MatchedAt   sql.NullTime
+}
+
+type TrackOverride struct {
+	SpotifyID string
+	TidalID   sql.NullString
+	Ignored   bool
+	CreatedAt sql.NullTime
+}
@@ -10,6 +10,32 @@ import (
 	"database/sql"
 )
 
+const abandonSyncRuns = `-- name: AbandonSyncRuns :exec
+UPDATE sync_runs SET status = 'abandoned', finished_at = CURRENT_TIMESTAMP
+WHERE status = 'running' AND job = ?
+`
+
+func (q *Queries) AbandonSyncRuns(ctx context.Context, job string) error {
+	_, err := q.db.ExecContext(ctx, abandonSyncRuns, job)
+	return err
+}
+
+const addPlaylistBaseTrack = `-- name: AddPlaylistBaseTrack :exec
+INSERT OR REPLACE INTO playlist_base_tracks (playlist_id, spotify_id, tidal_id)
+VALUES (?, ?, ?)
+`
+
+type AddPlaylistBaseTrackParams struct {
+	PlaylistID string
+	SpotifyID  string
+	TidalID    string
+}
+
+func (q *Queries) AddPlaylistBaseTrack(ctx context.Context, arg AddPlaylistBaseTrackParams) error {
+	_, err := q.db.ExecContext(ctx, addPlaylistBaseTrack, arg.PlaylistID, arg.SpotifyID, arg.TidalID)
+	return err
+}
+
 const addTrackToPlaylist = `-- name: AddTrackToPlaylist :exec
 INSERT INTO playlist_tracks (playlist_id, track_id)
 VALUES (?, ?)
@@ -28,24 +54,247 @@ func (q *Queries) AddTrackToPlaylist(ctx context.Context, arg AddTrackToPlaylist
 const createPlaylist = `-- name: CreatePlaylist :one
 INSERT INTO playlists (id)
 VALUES (?)
-RETURNING id
+RETURNING id, snapshot_id, snapshot_options, missing_tracks, synced_at
 `
 
-func (q *Queries) CreatePlaylist(ctx context.Context, id string) (string, error) {
+func (q *Queries) CreatePlaylist(ctx context.Context, id string) (Playlist, error) {
 	row := q.db.QueryRowContext(ctx, createPlaylist, id)
-	err := row.Scan(&id)
-	return id, err
+	var i Playlist
+	err := row.Scan(
+		&i.ID,
+		&i.SnapshotID,
+		&i.SnapshotOptions,
+		&i.MissingTracks,
+		&i.SyncedAt,
+	)
+	return i, err
+}
+
+const createSyncRun = `-- name: CreateSyncRun :one
+INSERT INTO sync_runs (status, job)
+VALUES ('running', ?)
+RETURNING id, status, started_at, finished_at, job
+`
+
+func (q *Queries) CreateSyncRun(ctx context.Context, job string) (SyncRun, error) {
+	row := q.db.QueryRowContext(ctx, createSyncRun, job)
+	var i SyncRun
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.Job,
+	)
+	return i, err
+}
+
+const createSyncRunItem = `-- name: CreateSyncRunItem :exec
+INSERT OR IGNORE INTO sync_run_items (run_id, playlist_id, spotify_id, position, status)
+VALUES (?, ?, ?, ?, 'pending')
+`
+
+type CreateSyncRunItemParams struct {
+	RunID      int64
+	PlaylistID string
+	SpotifyID  string
+	Position   int64
+}
+
+func (q *Queries) CreateSyncRunItem(ctx context.Context, arg CreateSyncRunItemParams) error {
+	_, err := q.db.ExecContext(ctx, createSyncRunItem,
+		arg.RunID,
+		arg.PlaylistID,
+		arg.SpotifyID,
+		arg.Position,
+	)
+	return err
+}
+
+const deleteAllTrackOverrides = `-- name: DeleteAllTrackOverrides :exec
+DELETE FROM track_overrides
+`
+
+func (q *Queries) DeleteAllTrackOverrides(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteAllTrackOverrides)
+	return err
+}
+
+const deleteFinishedSyncRunItems = `-- name: DeleteFinishedSyncRunItems :exec
+DELETE FROM sync_run_items
+WHERE run_id IN (SELECT id FROM sync_runs WHERE status != 'running')
+`
+
+func (q *Queries) DeleteFinishedSyncRunItems(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteFinishedSyncRunItems)
+	return err
+}
+
+const deleteMissingTrack = `-- name: DeleteMissingTrack :exec
+DELETE FROM missing_tracks
+WHERE spotify_id = ?
+`
+
+func (q *Queries) DeleteMissingTrack(ctx context.Context, spotifyID string) error {
+	_, err := q.db.ExecContext(ctx, deleteMissingTrack, spotifyID)
+	return err
+}
+
+const deletePlaylistBaseTracks = `-- name: DeletePlaylistBaseTracks :exec
+DELETE FROM playlist_base_tracks
+WHERE playlist_id = ?
+`
+
+func (q *Queries) DeletePlaylistBaseTracks(ctx context.Context, playlistID string) error {
+	_, err := q.db.ExecContext(ctx, deletePlaylistBaseTracks, playlistID)
+	return err
+}
+
+const deletePlaylistTrack = `-- name: DeletePlaylistTrack :exec
+DELETE FROM playlist_tracks
+WHERE playlist_id = ? AND track_id = ?
+`
+
+type DeletePlaylistTrackParams struct {
+	PlaylistID sql.NullString
+	TrackID    sql.NullString
+}
+
+func (q *Queries) DeletePlaylistTrack(ctx context.Context, arg DeletePlaylistTrackParams) error {
+	_, err := q.db.ExecContext(ctx, deletePlaylistTrack, arg.PlaylistID, arg.TrackID)
+	return err
+}
+
+const deleteTrackFromPlaylists = `-- name: DeleteTrackFromPlaylists :exec
+DELETE FROM playlist_tracks
+WHERE track_id = ?
+`
+
+func (q *Queries) DeleteTrackFromPlaylists(ctx context.Context, trackID sql.NullString) error {
+	_, err := q.db.ExecContext(ctx, deleteTrackFromPlaylists, trackID)
+	return err
+}
+
+const deleteTrackMapping = `-- name: DeleteTrackMapping :exec
+DELETE FROM track_mappings
+WHERE spotify_id = ?
+`
+
+func (q *Queries) DeleteTrackMapping(ctx context.Context, spotifyID string) error {
+	_, err := q.db.ExecContext(ctx, deleteTrackMapping, spotifyID)
+	return err
+}
+
+const deleteTrackOverride = `-- name: DeleteTrackOverride :exec
+DELETE FROM track_overrides
+WHERE spotify_id = ?
+`
+
+func (q *Queries) DeleteTrackOverride(ctx context.Context, spotifyID string) error {
+	_, err := q.db.ExecContext(ctx, deleteTrackOverride, spotifyID)
+	return err
+}
+
+const finishSyncRun = `-- name: FinishSyncRun :exec
+UPDATE sync_runs SET status = ?, finished_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`
+
+type FinishSyncRunParams struct {
+	Status string
+	ID     int64
+}
+
+func (q *Queries) FinishSyncRun(ctx context.Context, arg FinishSyncRunParams) error {
+	_, err := q.db.ExecContext(ctx, finishSyncRun, arg.Status, arg.ID)
+	return err
+}
+
+const getLibraryMapping = `-- name: GetLibraryMapping :one
+SELECT kind, spotify_id, tidal_id, match_method, confidence, matched_at FROM library_mappings
+WHERE kind = ? AND spotify_id = ? LIMIT 1
+`
+
+type GetLibraryMappingParams struct {
+	Kind      string
+	SpotifyID string
+}
+
+func (q *Queries) GetLibraryMapping(ctx context.Context, arg GetLibraryMappingParams) (LibraryMapping, error) {
+	row := q.db.QueryRowContext(ctx, getLibraryMapping, arg.Kind, arg.SpotifyID)
+	var i LibraryMapping
+	err := row.Scan(
+		&i.Kind,
+		&i.SpotifyID,
+		&i.TidalID,
+		&i.MatchMethod,
+		&i.Confidence,
+		&i.MatchedAt,
+	)
+	return i, err
+}
+
+const getMissingTrack = `-- name: GetMissingTrack :one
+SELECT spotify_id, isrc, attempts, last_checked_at FROM missing_tracks
+WHERE spotify_id = ? LIMIT 1
+`
+
+func (q *Queries) GetMissingTrack(ctx context.Context, spotifyID string) (MissingTrack, error) {
+	row := q.db.QueryRowContext(ctx, getMissingTrack, spotifyID)
+	var i MissingTrack
+	err := row.Scan(
+		&i.SpotifyID,
+		&i.Isrc,
+		&i.Attempts,
+		&i.LastCheckedAt,
+	)
+	return i, err
+}
+
+const getPlaylistBaseTracks = `-- name: GetPlaylistBaseTracks :many
+SELECT playlist_id, spotify_id, tidal_id FROM playlist_base_tracks
+WHERE playlist_id = ?
+`
+
+func (q *Queries) GetPlaylistBaseTracks(ctx context.Context, playlistID string) ([]PlaylistBaseTrack, error) {
+	rows, err := q.db.QueryContext(ctx, getPlaylistBaseTracks, playlistID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PlaylistBaseTrack
+	for rows.Next() {
+		var i PlaylistBaseTrack
+		if err := rows.Scan(&i.PlaylistID, &i.SpotifyID, &i.TidalID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getPlaylistById = `-- name: GetPlaylistById :one
-SELECT id FROM playlists
+SELECT id, snapshot_id, snapshot_options, missing_tracks, synced_at FROM playlists
 WHERE id = ? LIMIT 1
 `
 
-func (q *Queries) GetPlaylistById(ctx context.Context, id string) (string, error) {
+func (q *Queries) GetPlaylistById(ctx context.Context, id string) (Playlist, error) {
 	row := q.db.QueryRowContext(ctx, getPlaylistById, id)
-	err := row.Scan(&id)
-	return id, err
+	var i Playlist
+	err := row.Scan(
+		&i.ID,
+		&i.SnapshotID,
+		&i.SnapshotOptions,
+		&i.MissingTracks,
+		&i.SyncedAt,
+	)
+	return i, err
 }
 
 const getPlaylistTracks = `-- name: GetPlaylistTracks :many
@@ -76,6 +325,69 @@ func (q *Queries) GetPlaylistTracks(ctx context.Context, playlistID sql.NullStri
 	return items, nil
 }
 
+const getRunningSyncRun = `-- name: GetRunningSyncRun :one
+SELECT id, status, started_at, finished_at, job FROM sync_runs
+WHERE status = 'running' AND job = ?
+ORDER BY id DESC
+LIMIT 1
+`
+
+func (q *Queries) GetRunningSyncRun(ctx context.Context, job string) (SyncRun, error) {
+	row := q.db.QueryRowContext(ctx, getRunningSyncRun, job)
+	var i SyncRun
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.Job,
+	)
+	return i, err
+}
+
+const getSyncRunItems = `-- name: GetSyncRunItems :many
+SELECT run_id, playlist_id, spotify_id, position, status, tidal_id, reason, updated_at FROM sync_run_items
+WHERE run_id = ? AND playlist_id = ?
+ORDER BY position
+`
+
+type GetSyncRunItemsParams struct {
+	RunID      int64
+	PlaylistID string
+}
+
+func (q *Queries) GetSyncRunItems(ctx context.Context, arg GetSyncRunItemsParams) ([]SyncRunItem, error) {
+	rows, err := q.db.QueryContext(ctx, getSyncRunItems, arg.RunID, arg.PlaylistID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SyncRunItem
+	for rows.Next() {
+		var i SyncRunItem
+		if err := rows.Scan(
+			&i.RunID,
+			&i.PlaylistID,
+			&i.SpotifyID,
+			&i.Position,
+			&i.Status,
+			&i.TidalID,
+			&i.Reason,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getTrackById = `-- name: GetTrackById :one
 SELECT id FROM tracks
 WHERE id = ? LIMIT 1
@@ -86,3 +398,285 @@ func (q *Queries) GetTrackById(ctx context.Context, id string) (string, error) {
 	err := row.Scan(&id)
 	return id, err
 }
+
+const getTrackMapping = `-- name: GetTrackMapping :one
+SELECT spotify_id, isrc, tidal_id, match_method, confidence, matched_at FROM track_mappings
+WHERE spotify_id = ? LIMIT 1
+`
+
+func (q *Queries) GetTrackMapping(ctx context.Context, spotifyID string) (TrackMapping, error) {
+	row := q.db.QueryRowContext(ctx, getTrackMapping, spotifyID)
+	var i TrackMapping
+	err := row.Scan(
+		&i.SpotifyID,
+		&i.Isrc,
+		&i.TidalID,
+		&i.MatchMethod,
+		&i.Confidence,
+		&i.MatchedAt,
+	)
+	return i, err
+}
+
+const getTrackMappingByIsrc = `-- name: GetTrackMappingByIsrc :one
+SELECT spotify_id, isrc, tidal_id, match_method, confidence, matched_at FROM track_mappings
+WHERE isrc = ? LIMIT 1
+`
+
+func (q *Queries) GetTrackMappingByIsrc(ctx context.Context, isrc sql.NullString) (TrackMapping, error) {
+	row := q.db.QueryRowContext(ctx, getTrackMappingByIsrc, isrc)
+	var i TrackMapping
+	err := row.Scan(
+		&i.SpotifyID,
+		&i.Isrc,
+		&i.TidalID,
+		&i.MatchMethod,
+		&i.Confidence,
+		&i.MatchedAt,
+	)
+	return i, err
+}
+
+const getTrackMappingByTidalId = `-- name: GetTrackMappingByTidalId :one
+SELECT spotify_id, isrc, tidal_id, match_method, confidence, matched_at FROM track_mappings
+WHERE tidal_id = ?
+ORDER BY confidence DESC, matched_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetTrackMappingByTidalId(ctx context.Context, tidalID string) (TrackMapping, error) {
+	row := q.db.QueryRowContext(ctx, getTrackMappingByTidalId, tidalID)
+	var i TrackMapping
+	err := row.Scan(
+		&i.SpotifyID,
+		&i.Isrc,
+		&i.TidalID,
+		&i.MatchMethod,
+		&i.Confidence,
+		&i.MatchedAt,
+	)
+	return i, err
+}
+
+const getTrackOverride = `-- name: GetTrackOverride :one
+SELECT spotify_id, tidal_id, ignored, created_at FROM track_overrides
+WHERE spotify_id = ? LIMIT 1
+`
+
+func (q *Queries) GetTrackOverride(ctx context.Context, spotifyID string) (TrackOverride, error) {
+	row := q.db.QueryRowContext(ctx, getTrackOverride, spotifyID)
+	var i TrackOverride
+	err := row.Scan(
+		&i.SpotifyID,
+		&i.TidalID,
+		&i.Ignored,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getTrackOverrideByTidalId = `-- name: GetTrackOverrideByTidalId :one
+SELECT spotify_id, tidal_id, ignored, created_at FROM track_overrides
+WHERE tidal_id = ? AND ignored = 0 LIMIT 1
+`
+
+func (q *Queries) GetTrackOverrideByTidalId(ctx context.Context, tidalID sql.NullString) (TrackOverride, error) {
+	row := q.db.QueryRowContext(ctx, getTrackOverrideByTidalId, tidalID)
+	var i TrackOverride
+	err := row.Scan(
+		&i.SpotifyID,
+		&i.TidalID,
+		&i.Ignored,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listTrackOverrides = `-- name: ListTrackOverrides :many
+SELECT spotify_id, tidal_id, ignored, created_at FROM track_overrides
+ORDER BY spotify_id
+`
+
+func (q *Queries) ListTrackOverrides(ctx context.Context) ([]TrackOverride, error) {
+	rows, err := q.db.QueryContext(ctx, listTrackOverrides)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TrackOverride
+	for rows.Next() {
+		var i TrackOverride
+		if err := rows.Scan(
+			&i.SpotifyID,
+			&i.TidalID,
+			&i.Ignored,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const resetTrackPlaylistSnapshots = `-- name: ResetTrackPlaylistSnapshots :exec
+UPDATE playlists SET snapshot_id = NULL
+WHERE missing_tracks > 0 OR id IN (SELECT playlist_id FROM playlist_tracks WHERE track_id = ?)
+`
+
+func (q *Queries) ResetTrackPlaylistSnapshots(ctx context.Context, trackID sql.NullString) error {
+	_, err := q.db.ExecContext(ctx, resetTrackPlaylistSnapshots, trackID)
+	return err
+}
+
+const updatePlaylistSnapshot = `-- name: UpdatePlaylistSnapshot :exec
+UPDATE playlists SET snapshot_id = ?, snapshot_options = ?, missing_tracks = ?, synced_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`
+
+type UpdatePlaylistSnapshotParams struct {
+	SnapshotID      sql.NullString
+	SnapshotOptions sql.NullString
+	MissingTracks   int64
+	ID              string
+}
+
+func (q *Queries) UpdatePlaylistSnapshot(ctx context.Context, arg UpdatePlaylistSnapshotParams) error {
+	_, err := q.db.ExecContext(ctx, updatePlaylistSnapshot,
+		arg.SnapshotID,
+		arg.SnapshotOptions,
+		arg.MissingTracks,
+		arg.ID,
+	)
+	return err
+}
+
+const updateSyncRunItem = `-- name: UpdateSyncRunItem :exec
+UPDATE sync_run_items SET status = ?, tidal_id = ?, reason = ?, updated_at = CURRENT_TIMESTAMP
+WHERE run_id = ? AND playlist_id = ? AND spotify_id = ?
+`
+
+type UpdateSyncRunItemParams struct {
+	Status     string
+	TidalID    sql.NullString
+	Reason     sql.NullString
+	RunID      int64
+	PlaylistID string
+	SpotifyID  string
+}
+
+func (q *Queries) UpdateSyncRunItem(ctx context.Context, arg UpdateSyncRunItemParams) error {
+	_, err := q.db.ExecContext(ctx, updateSyncRunItem,
+		arg.Status,
+		arg.TidalID,
+		arg.Reason,
+		arg.RunID,
+		arg.PlaylistID,
+		arg.SpotifyID,
+	)
+	return err
+}
+
+const upsertLibraryMapping = `-- name: UpsertLibraryMapping :exec
+INSERT INTO library_mappings (kind, spotify_id, tidal_id, match_method, confidence, matched_at)
+VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT (kind, spotify_id) DO UPDATE SET
+  tidal_id = excluded.tidal_id,
+  match_method = excluded.match_method,
+  confidence = excluded.confidence,
+  matched_at = excluded.matched_at
+`
+
+type UpsertLibraryMappingParams struct {
+	Kind        string
+	SpotifyID   string
+	TidalID     string
+	MatchMethod string
+	Confidence  float64
+}
+
+func (q *Queries) UpsertLibraryMapping(ctx context.Context, arg UpsertLibraryMappingParams) error {
+	_, err := q.db.ExecContext(ctx, upsertLibraryMapping,
+		arg.Kind,
+		arg.SpotifyID,
+		arg.TidalID,
+		arg.MatchMethod,
+		arg.Confidence,
+	)
+	return err
+}
+
+const upsertMissingTrack = `-- name: UpsertMissingTrack :exec
+INSERT INTO missing_tracks (spotify_id, isrc, attempts, last_checked_at)
+VALUES (?, ?, 1, CURRENT_TIMESTAMP)
+ON CONFLICT (spotify_id) DO UPDATE SET
+  isrc = excluded.isrc,
+  attempts = missing_tracks.attempts + 1,
+  last_checked_at = excluded.last_checked_at
+`
+
+type UpsertMissingTrackParams struct {
+	SpotifyID string
+	Isrc      sql.NullString
+}
+
+func (q *Queries) UpsertMissingTrack(ctx context.Context, arg UpsertMissingTrackParams) error {
+	_, err := q.db.ExecContext(ctx, upsertMissingTrack, arg.SpotifyID, arg.Isrc)
+	return err
+}
+
+const upsertTrackMapping = `-- name: UpsertTrackMapping :exec
+INSERT INTO track_mappings (spotify_id, isrc, tidal_id, match_method, confidence, matched_at)
+VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT (spotify_id) DO UPDATE SET
+  isrc = excluded.isrc,
+  tidal_id = excluded.tidal_id,
+  match_method = excluded.match_method,
+  confidence = excluded.confidence,
+  matched_at = excluded.matched_at
+`
+
+type UpsertTrackMappingParams struct {
+	SpotifyID   string
+	Isrc        sql.NullString
+	TidalID     string
+	MatchMethod string
+	Confidence  float64
+}
+
+func (q *Queries) UpsertTrackMapping(ctx context.Context, arg UpsertTrackMappingParams) error {
+	_, err := q.db.ExecContext(ctx, upsertTrackMapping,
+		arg.SpotifyID,
+		arg.Isrc,
+		arg.TidalID,
+		arg.MatchMethod,
+		arg.Confidence,
+	)
+	return err
+}
+
+const upsertTrackOverride = `-- name: UpsertTrackOverride :exec
+INSERT INTO track_overrides (spotify_id, tidal_id, ignored, created_at)
+VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT (spotify_id) DO UPDATE SET
+  tidal_id = excluded.tidal_id,
+  ignored = excluded.ignored,
+  created_at = excluded.created_at
+`
+
+type UpsertTrackOverrideParams struct {
+	SpotifyID string
+	TidalID   sql.NullString
+	Ignored   bool
+}
+
+func (q *Queries) UpsertTrackOverride(ctx context.Context, arg UpsertTrackOverrideParams) error {
+	_, err := q.db.ExecContext(ctx, upsertTrackOverride, arg.SpotifyID, arg.TidalID, arg.Ignored)
+	return err
+}
@@ -11,7 +11,9 @@ require (
 	github.com/sethvargo/go-envconfig v1.0.1
 	github.com/urfave/cli/v2 v2.27.1
 	github.com/zmb3/spotify/v2 v2.4.1
+	golang.org/x/crypto v0.23.0
 	golang.org/x/oauth2 v0.0.0-20210810183815-faf39c7919d5
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -30,8 +32,9 @@ require (
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/xrash/smetrics v0.0.0-20201216005158-039620a65673 // indirect
 	golang.org/x/exp v0.0.0-20250210185358-939b2ce775ac
-	golang.org/x/net v0.19.0 // indirect
+	golang.org/x/net v0.21.0 // indirect
 	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/text v0.18.0
 	golang.org/x/time v0.10.0
 	google.golang.org/appengine v1.6.7 // indirect
 	google.golang.org/protobuf v1.31.0 // indirect
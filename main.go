@@ -4,7 +4,13 @@ import (
 	"context"
 	"database/sql"
 	_ "embed"
+	"errors"
+	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -23,15 +29,37 @@ import (
 //go:embed schema.sql
 var ddl string
 
-func initialize() (*config.Config, *config.JsonConfigService, *spotify.Service, *db.Queries) {
-	ctx := context.Background()
+// database is closed before exiting so pending writes are flushed
+var database *sql.DB
 
+// loadConfig loads the config and the json config which has credentials.
+// If a profile is provided the data of the profile is used instead of the default data.
+func loadConfig(profile string) (*config.Config, *config.JsonConfigService) {
 	// initialize config
 	c, err := config.Init()
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to load config")
 	}
 
+	if profile != "" {
+		if err := c.UseProfile(profile); err != nil {
+			log.Fatal().Err(err).Str("profile", profile).Msg("Failed to load profile")
+		}
+	}
+
+	// load json config which has credentials
+	jsonConfig := config.NewJsonConfigService(c.DataPath+"/config.json", c.ConfigKey())
+	if err := jsonConfig.Init(); err != nil {
+		log.Fatal().Err(err).Msg("Failed to load Spotify config")
+	}
+
+	return c, jsonConfig
+}
+
+// initializeDatabase loads the config and opens the database without authenticating with any service.
+func initializeDatabase(ctx context.Context, profile string) (*config.Config, *config.JsonConfigService, *db.Queries) {
+	c, jsonConfig := loadConfig(profile)
+
 	// database
 	dbConn, err := sql.Open("sqlite3", c.DataPath+"/tracks.db")
 	if err != nil {
@@ -42,15 +70,20 @@ func initialize() (*config.Config, *config.JsonConfigService, *spotify.Service,
 	if _, err := dbConn.ExecContext(ctx, ddl); err != nil {
 		log.Fatal().Err(err).Msg("Failed to create tables")
 	}
-	queries := db.New(dbConn)
-
-	// load json config which has credentials
-	jsonConfig := config.NewJsonConfigService(c.DataPath + "/config.json")
-	err = jsonConfig.Init()
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to load Spotify config")
+	if err := db.Migrate(ctx, dbConn); err != nil {
+		log.Fatal().Err(err).Msg("Failed to migrate database")
 	}
 
+	database = dbConn
+
+	return c, jsonConfig, db.New(dbConn)
+}
+
+// initialize loads the config, opens the database, and authenticates with Spotify.
+// If interactive is not set and Spotify is not logged in the command fails instead of prompting to log in.
+func initialize(ctx context.Context, profile string, interactive bool) (*config.Config, *config.JsonConfigService, *spotify.Service, *db.Queries) {
+	c, jsonConfig, queries := initializeDatabase(ctx, profile)
+
 	// initialize the spotify connection
 	spotifyService, err := spotify.Initialize(c.SpotifyClientId, c.SpotifyClientSecret, c.SpotifyRedirectUri, jsonConfig, c)
 	if err != nil {
@@ -58,7 +91,7 @@ func initialize() (*config.Config, *config.JsonConfigService, *spotify.Service,
 	}
 
 	// authenticate with spotify
-	err = spotifyService.Authenticate()
+	err = spotifyService.Authenticate(ctx, interactive)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to authenticate with Spotify")
 	}
@@ -66,14 +99,162 @@ func initialize() (*config.Config, *config.JsonConfigService, *spotify.Service,
 	return c, jsonConfig, spotifyService, queries
 }
 
+// printAuthStatus prints the token expiry and scopes of a logged in account.
+func printAuthStatus(expiry time.Time, scopes []string) {
+	if expiry.IsZero() {
+		fmt.Println("  token expires: unknown")
+	} else {
+		fmt.Printf("  token expires: %s\n", expiry.Local().Format(time.RFC1123))
+	}
+	if len(scopes) == 0 {
+		fmt.Println("  scopes: unknown")
+	} else {
+		fmt.Printf("  scopes: %s\n", strings.Join(scopes, " "))
+	}
+}
+
+// syncJobOptions returns the options of the sync job, starting from the options of the flags.
+// Flags that are set take precedence over the job, match settings are resolved by the sync file.
+func syncJobOptions(cCtx *cli.Context, c *config.Config, syncFile *config.SyncFile, job config.SyncJob, flagOpts convert.SyncOptions) convert.SyncOptions {
+	opts := flagOpts
+
+	if !cCtx.IsSet("spotify-playlist-id") {
+		opts.Playlists = job.Playlists
+	}
+	if !cCtx.IsSet("mirror") {
+		opts.Mirror = job.Options.Mirror
+	}
+	if !cCtx.IsSet("preserve-order") {
+		opts.PreserveOrder = job.Options.PreserveOrder
+	}
+	if !cCtx.IsSet("full") {
+		opts.Full = job.Options.Full
+	}
+	if !cCtx.IsSet("bidirectional") {
+		opts.Bidirectional = job.Options.Bidirectional
+	}
+	if !cCtx.IsSet("save-missing-tracks") {
+		opts.SaveMissingTracks = job.Options.SaveMissingTracks
+	}
+	if !cCtx.IsSet("save-tidal-playlist") {
+		opts.SaveTidalPlaylist = job.Options.SaveTidalPlaylist
+	}
+	if !cCtx.IsSet("save-navidrome-playlist") {
+		opts.SaveNavidromePlaylist = job.Options.SaveNavidromePlaylist
+	}
+	if !cCtx.IsSet("liked-songs") {
+		opts.LikedSongs = job.Options.LikedSongs
+	}
+	if !cCtx.IsSet("saved-albums") {
+		opts.SavedAlbums = job.Options.SavedAlbums
+	}
+	if !cCtx.IsSet("followed-artists") {
+		opts.FollowedArtists = job.Options.FollowedArtists
+	}
+
+	opts.MatchThreshold, opts.CountryCode = syncFile.MatchSettings(job, c)
+	opts.Job = job.Name
+
+	return opts
+}
+
+// spotifyLoginFlags returns the flags of the spotify login commands.
+func spotifyLoginFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "paste",
+			Usage: "Paste the URL the browser is redirected to instead of starting a callback server, for headless servers",
+		},
+	}
+}
+
+// spotifyLogin logs in to Spotify, replacing the logged in account.
+func spotifyLogin(cCtx *cli.Context) error {
+	c, jsonConfigService := loadConfig(cCtx.String("profile"))
+
+	spotifyService, err := spotify.Initialize(c.SpotifyClientId, c.SpotifyClientSecret, c.SpotifyRedirectUri, jsonConfigService, c)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize Spotify service")
+	}
+
+	if _, err := spotifyService.Login(cCtx.Context, cCtx.Bool("paste")); err != nil {
+		return err
+	}
+	return nil
+}
+
+// writeDryRunPlan prints the plan of a dry run and writes it to the plan file if one was provided.
+// Nothing is done if the sync was not a dry run.
+func writeDryRunPlan(plan *convert.Plan, planFile string) error {
+	if plan == nil {
+		return nil
+	}
+
+	plan.Print(os.Stdout)
+	if planFile == "" {
+		return nil
+	}
+
+	if err := convert.WritePlan(planFile, plan); err != nil {
+		return err
+	}
+	log.Info().Str("path", planFile).Msg("wrote dry run plan")
+
+	return nil
+}
+
+// shutdownContext returns a context that is cancelled on the first interrupt or termination signal.
+// The sync stops after the current track and saves its progress, a second signal exits immediately, killing the running profile.
+func shutdownContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		log.Warn().Msg("Shutting down after the current track, send the signal again to exit immediately")
+		cancel()
+
+		<-signals
+		log.Warn().Msg("Exiting immediately")
+		killRunningProfile()
+		os.Exit(1)
+	}()
+
+	return ctx
+}
+
 func main() {
 	var saveMissingTracks bool
 	var saveTidalPlaylist bool
 	var saveNavidromePlaylist bool
+	var recheckMissingTracks bool
+	var mirror bool
+	var preserveOrder bool
+	var full bool
+	var dryRun bool
+	var planFile string
+	var bidirectional bool
+	var likedSongs string
+	var savedAlbums bool
+	var followedArtists bool
+	var resume bool
 
 	app := &cli.App{
 		Name:  "spotify-playlist-sync",
 		Usage: "sync spotify playlists to other services",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "non-interactive",
+				Usage:   "Fail instead of prompting to log in when a service is not logged in, e.g. when run by cron",
+				EnvVars: []string{"NON_INTERACTIVE"},
+			},
+			&cli.StringFlag{
+				Name:    "profile",
+				Usage:   "Use the tokens, database, and output files of the profile in DATA_PATH/profiles/<name>",
+				EnvVars: []string{"PROFILE"},
+			},
+		},
 		Commands: []*cli.Command{
 			{
 				Name:  "tidal",
@@ -94,24 +275,99 @@ func main() {
 						Usage:       "Save a version of the tidal playlist for importing in Navidrome",
 						Destination: &saveNavidromePlaylist,
 					},
+					&cli.BoolFlag{
+						Name:        "recheck-missing-tracks",
+						Usage:       "Search for previously missing tracks even if the retry interval has not elapsed",
+						Destination: &recheckMissingTracks,
+					},
+					&cli.BoolFlag{
+						Name:        "mirror",
+						Usage:       "Remove tracks from the Tidal playlist that were removed from the Spotify playlist",
+						Destination: &mirror,
+					},
+					&cli.BoolFlag{
+						Name:        "preserve-order",
+						Usage:       "Reorder the Tidal playlist to match the Spotify playlist order",
+						Destination: &preserveOrder,
+					},
+					&cli.BoolFlag{
+						Name:        "full",
+						Usage:       "Sync all playlists even if they are unchanged since the last sync",
+						Destination: &full,
+					},
+					&cli.BoolFlag{
+						Name:        "dry-run",
+						Usage:       "Print the changes that would be made without modifying Tidal or the database",
+						Destination: &dryRun,
+					},
+					&cli.StringFlag{
+						Name:        "plan-file",
+						Usage:       "Write the dry run plan to the provided path as JSON",
+						Destination: &planFile,
+					},
+					&cli.BoolFlag{
+						Name:        "bidirectional",
+						Usage:       "Merge changes made on either side of linked playlists instead of copying Spotify playlists to Tidal",
+						Destination: &bidirectional,
+					},
+					&cli.StringFlag{
+						Name:        "liked-songs",
+						Usage:       "Sync Spotify Liked Songs to Tidal \"favorites\" or a Tidal \"playlist\"",
+						Destination: &likedSongs,
+						Action: func(cCtx *cli.Context, v string) error {
+							if v != convert.LikedSongsFavorites && v != convert.LikedSongsPlaylist {
+								return fmt.Errorf("liked-songs must be %q or %q", convert.LikedSongsFavorites, convert.LikedSongsPlaylist)
+							}
+							return nil
+						},
+					},
+					&cli.BoolFlag{
+						Name:        "saved-albums",
+						Usage:       "Add Spotify saved albums to Tidal favorites",
+						Destination: &savedAlbums,
+					},
+					&cli.BoolFlag{
+						Name:        "followed-artists",
+						Usage:       "Add Spotify followed artists to Tidal favorites",
+						Destination: &followedArtists,
+					},
+					&cli.BoolFlag{
+						Name:        "resume",
+						Usage:       "Continue the last interrupted sync without searching for already matched tracks again",
+						Destination: &resume,
+					},
 					&cli.StringSliceFlag{
 						Name:    "spotify-playlist-id",
 						Aliases: []string{"spi"},
 						Usage:   "List of Spotify playlist IDs to sync. Defaults to all user playlists if not provided.",
 					},
+					&cli.StringFlag{
+						Name:  "config",
+						Usage: "Run the sync jobs declared in the provided YAML file, flags that are set override the options of every job",
+					},
 				},
 				Action: func(cCtx *cli.Context) error {
-					c, jsonConfigService, spotifyService, queries := initialize()
+					// the sync file is validated before logging in so mistakes are reported up front
+					var syncFile *config.SyncFile
+					if syncFilePath := cCtx.String("config"); syncFilePath != "" {
+						f, err := config.LoadSyncFile(syncFilePath)
+						if err != nil {
+							return cli.Exit(err, 1)
+						}
+						syncFile = f
+					}
+
+					c, jsonConfigService, spotifyService, queries := initialize(cCtx.Context, cCtx.String("profile"), !cCtx.Bool("non-interactive"))
 
 					spotifyPlaylistIDs := cCtx.StringSlice("spotify-playlist-id")
 
-					tidalService, err := tidal.Initialize(c.TidalClientId, c.TidalClientSecret, jsonConfigService)
+					tidalService, err := tidal.Initialize(cCtx.Context, c.TidalClientId, c.TidalClientSecret, c.TidalCountryCode, jsonConfigService)
 					if err != nil {
 						log.Fatal().Err(err).Msg("Failed to initialize Tidal service")
 					}
 
 					// authenticate with Tidal
-					err = tidalService.DeviceAuthenticate()
+					err = tidalService.DeviceAuthenticate(cCtx.Context, !cCtx.Bool("non-interactive"))
 					if err != nil {
 						log.Fatal().Err(err).Msg("Failed to authenticate with Tidal")
 					}
@@ -122,14 +378,394 @@ func main() {
 						log.Fatal().Err(err).Msg("Failed to initialize convert service")
 					}
 
-					err = convertService.SpotifyToTidal(cCtx.Context, saveMissingTracks, saveTidalPlaylist, saveMissingTracks, spotifyPlaylistIDs)
+					opts := convert.SyncOptions{
+						SaveMissingTracks:         saveMissingTracks,
+						SaveTidalPlaylist:         saveTidalPlaylist,
+						SaveNavidromePlaylist:     saveNavidromePlaylist,
+						Playlists:                 config.PlaylistSelector{IDs: spotifyPlaylistIDs},
+						RecheckMissingTracks:      recheckMissingTracks,
+						MissingTrackRetryInterval: c.MissingTrackRetryInterval,
+						Mirror:                    mirror,
+						PreserveOrder:             preserveOrder,
+						Full:                      full,
+						DryRun:                    dryRun,
+						MatchThreshold:            c.MatchThreshold,
+						CountryCode:               c.TidalCountryCode,
+						Concurrency:               c.MatchConcurrency,
+						Bidirectional:             bidirectional,
+						LikedSongs:                likedSongs,
+						SavedAlbums:               savedAlbums,
+						FollowedArtists:           followedArtists,
+						Resume:                    resume,
+					}
+
+					if syncFile == nil {
+						plan, err := convertService.SpotifyToTidal(cCtx.Context, opts)
+						if errors.Is(err, context.Canceled) {
+							return err
+						}
+						if err != nil {
+							log.Fatal().Err(err).Msg("Failed to convert Spotify to Tidal")
+						}
+						return writeDryRunPlan(plan, planFile)
+					}
+
+					// the plans of every job are combined so a single plan file is written
+					var plan *convert.Plan
+					for _, job := range syncFile.Jobs {
+						log.Info().Str("job", job.Name).Msg("Running sync job")
+						jobPlan, err := convertService.SpotifyToTidal(cCtx.Context, syncJobOptions(cCtx, c, syncFile, job, opts))
+						if errors.Is(err, context.Canceled) {
+							return err
+						}
+						if err != nil {
+							log.Fatal().Err(err).Str("job", job.Name).Msg("Failed to convert Spotify to Tidal")
+						}
+						if jobPlan != nil {
+							if plan == nil {
+								plan = &convert.Plan{}
+							}
+							plan.Playlists = append(plan.Playlists, jobPlan.Playlists...)
+							plan.Favorites = append(plan.Favorites, jobPlan.Favorites...)
+						}
+					}
+
+					return writeDryRunPlan(plan, planFile)
+				},
+			},
+			{
+				Name:  "spotify",
+				Usage: "sync tidal playlists to spotify",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:    "tidal-playlist-id",
+						Aliases: []string{"tpi"},
+						Usage:   "List of Tidal playlist IDs to sync. Defaults to all user playlists if not provided.",
+					},
+					&cli.BoolFlag{
+						Name:  "save-missing-tracks",
+						Usage: "Save missing tracks during the conversion",
+					},
+				},
+				Action: func(cCtx *cli.Context) error {
+					c, jsonConfigService, spotifyService, queries := initialize(cCtx.Context, cCtx.String("profile"), !cCtx.Bool("non-interactive"))
+
+					tidalService, err := tidal.Initialize(cCtx.Context, c.TidalClientId, c.TidalClientSecret, c.TidalCountryCode, jsonConfigService)
+					if err != nil {
+						log.Fatal().Err(err).Msg("Failed to initialize Tidal service")
+					}
+
+					// authenticate with Tidal
+					err = tidalService.DeviceAuthenticate(cCtx.Context, !cCtx.Bool("non-interactive"))
+					if err != nil {
+						log.Fatal().Err(err).Msg("Failed to authenticate with Tidal")
+					}
+
+					// convert
+					convertService, err := convert.Initialize(spotifyService, tidalService, jsonConfigService, queries)
+					if err != nil {
+						log.Fatal().Err(err).Msg("Failed to initialize convert service")
+					}
+
+					err = convertService.TidalToSpotify(cCtx.Context, convert.TidalToSpotifyOptions{
+						TidalPlaylistIDs:  cCtx.StringSlice("tidal-playlist-id"),
+						MatchThreshold:    c.MatchThreshold,
+						SaveMissingTracks: cCtx.Bool("save-missing-tracks"),
+					})
+					if errors.Is(err, context.Canceled) {
+						return err
+					}
 					if err != nil {
-						log.Fatal().Err(err).Msg("Failed to convert Spotify to Tidal")
+						log.Fatal().Err(err).Msg("Failed to convert Tidal to Spotify")
 					}
 
 					return nil
 				},
 			},
+			{
+				Name:  "auth",
+				Usage: "manage the logged in spotify and tidal accounts",
+				Subcommands: []*cli.Command{
+					// kept for compatibility, same as auth login spotify
+					{
+						Name:   "spotify",
+						Usage:  "log in to spotify",
+						Hidden: true,
+						Flags:  spotifyLoginFlags(),
+						Action: spotifyLogin,
+					},
+					{
+						Name:  "status",
+						Usage: "show the logged in accounts, token expiry, and scopes",
+						Action: func(cCtx *cli.Context) error {
+							c, jsonConfigService := loadConfig(cCtx.String("profile"))
+							loggedIn := true
+
+							spotifyService, err := spotify.Initialize(c.SpotifyClientId, c.SpotifyClientSecret, c.SpotifyRedirectUri, jsonConfigService, c)
+							if err != nil {
+								log.Fatal().Err(err).Msg("Failed to initialize Spotify service")
+							}
+							spotifyStatus, err := spotifyService.Status(cCtx.Context)
+							if err != nil {
+								loggedIn = false
+								fmt.Printf("Spotify: not logged in: %v\n", err)
+							} else {
+								fmt.Printf("Spotify: logged in as %s\n", spotifyStatus.UserID)
+								printAuthStatus(spotifyStatus.Expiry, spotifyStatus.Scopes)
+								if len(spotifyStatus.MissingScopes) > 0 {
+									fmt.Printf("  missing scopes: %s (log in again with `auth login spotify`)\n", strings.Join(spotifyStatus.MissingScopes, " "))
+								}
+							}
+
+							// the Tidal client is authenticated before the account, which can fail on its own
+							var tidalStatus *tidal.AuthStatus
+							tidalService, err := tidal.Initialize(cCtx.Context, c.TidalClientId, c.TidalClientSecret, c.TidalCountryCode, jsonConfigService)
+							if err == nil {
+								tidalStatus, err = tidalService.Status(cCtx.Context)
+							}
+							if err != nil {
+								loggedIn = false
+								fmt.Printf("Tidal: not logged in: %v\n", err)
+							} else {
+								fmt.Printf("Tidal: logged in as %s (%s)\n", tidalStatus.UserID, tidalStatus.CountryCode)
+								printAuthStatus(tidalStatus.Expiry, tidalStatus.Scopes)
+							}
+
+							if !loggedIn {
+								return fmt.Errorf("not logged in to every service")
+							}
+							return nil
+						},
+					},
+					{
+						Name:  "login",
+						Usage: "log in to a service, replacing the logged in account",
+						Subcommands: []*cli.Command{
+							{
+								Name:   "spotify",
+								Usage:  "log in to spotify",
+								Flags:  spotifyLoginFlags(),
+								Action: spotifyLogin,
+							},
+							{
+								Name:  "tidal",
+								Usage: "log in to tidal",
+								Action: func(cCtx *cli.Context) error {
+									c, jsonConfigService := loadConfig(cCtx.String("profile"))
+
+									tidalService, err := tidal.Initialize(cCtx.Context, c.TidalClientId, c.TidalClientSecret, c.TidalCountryCode, jsonConfigService)
+									if err != nil {
+										log.Fatal().Err(err).Msg("Failed to initialize Tidal service")
+									}
+
+									return tidalService.Login(cCtx.Context)
+								},
+							},
+						},
+					},
+					{
+						Name:      "logout",
+						Usage:     "remove the saved tokens of a service",
+						ArgsUsage: "<spotify|tidal>",
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.NArg() != 1 {
+								return fmt.Errorf("expected spotify or tidal")
+							}
+							_, jsonConfigService := loadConfig(cCtx.String("profile"))
+
+							switch cCtx.Args().First() {
+							case "spotify":
+								if err := spotify.Logout(jsonConfigService); err != nil {
+									return err
+								}
+							case "tidal":
+								if err := tidal.Logout(jsonConfigService); err != nil {
+									return err
+								}
+							default:
+								return fmt.Errorf("expected spotify or tidal")
+							}
+							log.Info().Str("service", cCtx.Args().First()).Msg("logged out")
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "profiles",
+				Usage: "manage profiles of different spotify and tidal accounts",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "list all profiles",
+						Action: func(cCtx *cli.Context) error {
+							c, err := config.Init()
+							if err != nil {
+								return err
+							}
+
+							profiles, err := c.Profiles()
+							if err != nil {
+								return err
+							}
+							for _, profile := range profiles {
+								fmt.Println(profile)
+							}
+							return nil
+						},
+					},
+					{
+						Name:      "run",
+						Usage:     "run a command for every profile, one after another",
+						ArgsUsage: "<command> [arguments]",
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.NArg() == 0 {
+								return fmt.Errorf("expected a command, e.g. profiles run -- tidal --mirror")
+							}
+							c, err := config.Init()
+							if err != nil {
+								return err
+							}
+
+							profiles, err := c.Profiles()
+							if err != nil {
+								return err
+							}
+							if len(profiles) == 0 {
+								return fmt.Errorf("no profiles found in %s/profiles", c.DataPath)
+							}
+
+							return runForProfiles(cCtx.Context, profiles, cCtx.Args().Slice())
+						},
+					},
+				},
+			},
+			{
+				Name:  "match",
+				Usage: "manage manual spotify to tidal track match overrides",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "set",
+						Usage:     "pin a spotify track to a tidal track",
+						ArgsUsage: "<spotify-track-id> <tidal-track-id>",
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.NArg() != 2 {
+								return fmt.Errorf("expected a spotify track id and a tidal track id")
+							}
+							_, _, queries := initializeDatabase(cCtx.Context, cCtx.String("profile"))
+
+							if err := convert.SetTrackOverride(cCtx.Context, queries, cCtx.Args().Get(0), cCtx.Args().Get(1)); err != nil {
+								return err
+							}
+							log.Info().Str("spotify_track_id", cCtx.Args().Get(0)).Str("tidal_track_id", cCtx.Args().Get(1)).Msg("track override saved")
+							return nil
+						},
+					},
+					{
+						Name:      "ignore",
+						Usage:     "never sync a spotify track or report it as missing",
+						ArgsUsage: "<spotify-track-id>",
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.NArg() != 1 {
+								return fmt.Errorf("expected a spotify track id")
+							}
+							_, _, queries := initializeDatabase(cCtx.Context, cCtx.String("profile"))
+
+							if err := convert.IgnoreTrack(cCtx.Context, queries, cCtx.Args().First()); err != nil {
+								return err
+							}
+							log.Info().Str("spotify_track_id", cCtx.Args().First()).Msg("track ignored")
+							return nil
+						},
+					},
+					{
+						Name:      "clear",
+						Usage:     "remove the override for a spotify track",
+						ArgsUsage: "[spotify-track-id]",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "all",
+								Usage: "Remove every override",
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.Bool("all") == (cCtx.NArg() == 1) || cCtx.NArg() > 1 {
+								return fmt.Errorf("expected a spotify track id or --all")
+							}
+							_, _, queries := initializeDatabase(cCtx.Context, cCtx.String("profile"))
+
+							if cCtx.Bool("all") {
+								if err := convert.ClearTrackOverrides(cCtx.Context, queries); err != nil {
+									return err
+								}
+								log.Info().Msg("all track overrides removed")
+								return nil
+							}
+
+							if err := convert.ClearTrackOverride(cCtx.Context, queries, cCtx.Args().First()); err != nil {
+								return err
+							}
+							log.Info().Str("spotify_track_id", cCtx.Args().First()).Msg("track override removed")
+							return nil
+						},
+					},
+					{
+						Name:  "list",
+						Usage: "list all overrides",
+						Action: func(cCtx *cli.Context) error {
+							_, _, queries := initializeDatabase(cCtx.Context, cCtx.String("profile"))
+
+							overrides, err := convert.ListTrackOverrides(cCtx.Context, queries)
+							if err != nil {
+								return err
+							}
+							for _, override := range overrides {
+								if override.Ignore {
+									fmt.Printf("%s ignored\n", override.SpotifyID)
+								} else {
+									fmt.Printf("%s -> %s\n", override.SpotifyID, override.TidalID)
+								}
+							}
+							return nil
+						},
+					},
+					{
+						Name:      "export",
+						Usage:     "write all overrides to a YAML or JSON file",
+						ArgsUsage: "<file>",
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.NArg() != 1 {
+								return fmt.Errorf("expected a file path")
+							}
+							_, _, queries := initializeDatabase(cCtx.Context, cCtx.String("profile"))
+
+							if err := convert.ExportTrackOverrides(cCtx.Context, queries, cCtx.Args().First()); err != nil {
+								return err
+							}
+							log.Info().Str("path", cCtx.Args().First()).Msg("track overrides exported")
+							return nil
+						},
+					},
+					{
+						Name:      "import",
+						Usage:     "load overrides from a YAML or JSON file",
+						ArgsUsage: "<file>",
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.NArg() != 1 {
+								return fmt.Errorf("expected a file path")
+							}
+							_, _, queries := initializeDatabase(cCtx.Context, cCtx.String("profile"))
+
+							count, err := convert.ImportTrackOverrides(cCtx.Context, queries, cCtx.Args().First())
+							if err != nil {
+								return err
+							}
+							log.Info().Str("path", cCtx.Args().First()).Msgf("imported %d track overrides", count)
+							return nil
+						},
+					},
+				},
+			},
 		},
 	}
 
@@ -145,7 +781,19 @@ func main() {
 		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 	}
 
-	if err := app.Run(os.Args); err != nil {
+	err := app.RunContext(shutdownContext(), os.Args)
+
+	if database != nil {
+		if err := database.Close(); err != nil {
+			log.Error().Err(err).Msg("Failed to close database")
+		}
+	}
+
+	if errors.Is(err, context.Canceled) {
+		log.Warn().Msg("Sync interrupted")
+		os.Exit(130)
+	}
+	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to run app")
 	}
 
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// runningProfile is the process of the profile that is running, killed when exiting immediately.
+var runningProfile struct {
+	mu      sync.Mutex
+	process *os.Process
+}
+
+// killRunningProfile kills the profile that is running so it does not keep syncing after the command exits.
+func killRunningProfile() {
+	runningProfile.mu.Lock()
+	defer runningProfile.mu.Unlock()
+
+	if runningProfile.process == nil {
+		return
+	}
+	if err := killProfile(runningProfile.process); err != nil {
+		log.Error().Err(err).Int("pid", runningProfile.process.Pid).Msg("failed to kill profile")
+	}
+}
+
+func setRunningProfile(process *os.Process) {
+	runningProfile.mu.Lock()
+	defer runningProfile.mu.Unlock()
+	runningProfile.process = process
+}
+
+// runForProfiles runs the command for each profile, one after another.
+// Each profile runs in its own process so a profile that fails does not stop the others.
+// Profiles never prompt to log in as nobody may be watching, and are stopped gracefully when shutting down.
+func runForProfiles(ctx context.Context, profiles []string, args []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, profile := range profiles {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		log.Info().Str("profile", profile).Msgf("running %s", strings.Join(args, " "))
+
+		cmd := exec.CommandContext(ctx, executable, append([]string{"--profile", profile, "--non-interactive"}, args...)...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		// the profile only receives signals forwarded here, so it is not interrupted twice
+		cmd.SysProcAttr = profileProcAttr()
+		cmd.Cancel = func() error {
+			return cmd.Process.Signal(os.Interrupt)
+		}
+
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		setRunningProfile(cmd.Process)
+		err := cmd.Wait()
+		setRunningProfile(nil)
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Error().Err(err).Str("profile", profile).Msg("Profile failed")
+			failed = append(failed, profile)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d profiles failed: %s", len(failed), len(profiles), strings.Join(failed, ", "))
+	}
+
+	return nil
+}
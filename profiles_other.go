@@ -0,0 +1,18 @@
+//go:build !unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// profileProcAttr returns the default attributes, profiles share the console of the command.
+func profileProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+// killProfile kills the process of the profile.
+func killProfile(process *os.Process) error {
+	return process.Kill()
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// profileProcAttr starts profiles in their own process group so they do not receive the terminal's interrupt.
+func profileProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProfile kills the process group of the profile, including any processes it started.
+func killProfile(process *os.Process) error {
+	return syscall.Kill(-process.Pid, syscall.SIGKILL)
+}
@@ -18,3 +18,151 @@ WHERE id = ? LIMIT 1;
 INSERT INTO playlists (id)
 VALUES (?)
 RETURNING *;
+
+-- name: GetTrackMapping :one
+SELECT * FROM track_mappings
+WHERE spotify_id = ? LIMIT 1;
+
+-- name: GetTrackMappingByIsrc :one
+SELECT * FROM track_mappings
+WHERE isrc = ? LIMIT 1;
+
+-- name: GetTrackMappingByTidalId :one
+SELECT * FROM track_mappings
+WHERE tidal_id = ?
+ORDER BY confidence DESC, matched_at DESC
+LIMIT 1;
+
+-- name: UpsertTrackMapping :exec
+INSERT INTO track_mappings (spotify_id, isrc, tidal_id, match_method, confidence, matched_at)
+VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT (spotify_id) DO UPDATE SET
+  isrc = excluded.isrc,
+  tidal_id = excluded.tidal_id,
+  match_method = excluded.match_method,
+  confidence = excluded.confidence,
+  matched_at = excluded.matched_at;
+
+-- name: GetLibraryMapping :one
+SELECT * FROM library_mappings
+WHERE kind = ? AND spotify_id = ? LIMIT 1;
+
+-- name: UpsertLibraryMapping :exec
+INSERT INTO library_mappings (kind, spotify_id, tidal_id, match_method, confidence, matched_at)
+VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT (kind, spotify_id) DO UPDATE SET
+  tidal_id = excluded.tidal_id,
+  match_method = excluded.match_method,
+  confidence = excluded.confidence,
+  matched_at = excluded.matched_at;
+
+-- name: GetMissingTrack :one
+SELECT * FROM missing_tracks
+WHERE spotify_id = ? LIMIT 1;
+
+-- name: UpsertMissingTrack :exec
+INSERT INTO missing_tracks (spotify_id, isrc, attempts, last_checked_at)
+VALUES (?, ?, 1, CURRENT_TIMESTAMP)
+ON CONFLICT (spotify_id) DO UPDATE SET
+  isrc = excluded.isrc,
+  attempts = missing_tracks.attempts + 1,
+  last_checked_at = excluded.last_checked_at;
+
+-- name: DeleteMissingTrack :exec
+DELETE FROM missing_tracks
+WHERE spotify_id = ?;
+
+-- name: DeletePlaylistTrack :exec
+DELETE FROM playlist_tracks
+WHERE playlist_id = ? AND track_id = ?;
+
+-- name: DeleteTrackFromPlaylists :exec
+DELETE FROM playlist_tracks
+WHERE track_id = ?;
+
+-- name: ResetTrackPlaylistSnapshots :exec
+UPDATE playlists SET snapshot_id = NULL
+WHERE missing_tracks > 0 OR id IN (SELECT playlist_id FROM playlist_tracks WHERE track_id = ?);
+
+-- name: UpdatePlaylistSnapshot :exec
+UPDATE playlists SET snapshot_id = ?, snapshot_options = ?, missing_tracks = ?, synced_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: DeleteTrackMapping :exec
+DELETE FROM track_mappings
+WHERE spotify_id = ?;
+
+-- name: GetTrackOverride :one
+SELECT * FROM track_overrides
+WHERE spotify_id = ? LIMIT 1;
+
+-- name: GetTrackOverrideByTidalId :one
+SELECT * FROM track_overrides
+WHERE tidal_id = ? AND ignored = 0 LIMIT 1;
+
+-- name: ListTrackOverrides :many
+SELECT * FROM track_overrides
+ORDER BY spotify_id;
+
+-- name: UpsertTrackOverride :exec
+INSERT INTO track_overrides (spotify_id, tidal_id, ignored, created_at)
+VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT (spotify_id) DO UPDATE SET
+  tidal_id = excluded.tidal_id,
+  ignored = excluded.ignored,
+  created_at = excluded.created_at;
+
+-- name: DeleteTrackOverride :exec
+DELETE FROM track_overrides
+WHERE spotify_id = ?;
+
+-- name: DeleteAllTrackOverrides :exec
+DELETE FROM track_overrides;
+
+-- name: GetPlaylistBaseTracks :many
+SELECT * FROM playlist_base_tracks
+WHERE playlist_id = ?;
+
+-- name: AddPlaylistBaseTrack :exec
+INSERT OR REPLACE INTO playlist_base_tracks (playlist_id, spotify_id, tidal_id)
+VALUES (?, ?, ?);
+
+-- name: DeletePlaylistBaseTracks :exec
+DELETE FROM playlist_base_tracks
+WHERE playlist_id = ?;
+
+-- name: CreateSyncRun :one
+INSERT INTO sync_runs (status, job)
+VALUES ('running', ?)
+RETURNING *;
+
+-- name: GetRunningSyncRun :one
+SELECT * FROM sync_runs
+WHERE status = 'running' AND job = ?
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: FinishSyncRun :exec
+UPDATE sync_runs SET status = ?, finished_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: AbandonSyncRuns :exec
+UPDATE sync_runs SET status = 'abandoned', finished_at = CURRENT_TIMESTAMP
+WHERE status = 'running' AND job = ?;
+
+-- name: DeleteFinishedSyncRunItems :exec
+DELETE FROM sync_run_items
+WHERE run_id IN (SELECT id FROM sync_runs WHERE status != 'running');
+
+-- name: CreateSyncRunItem :exec
+INSERT OR IGNORE INTO sync_run_items (run_id, playlist_id, spotify_id, position, status)
+VALUES (?, ?, ?, ?, 'pending');
+
+-- name: GetSyncRunItems :many
+SELECT * FROM sync_run_items
+WHERE run_id = ? AND playlist_id = ?
+ORDER BY position;
+
+-- name: UpdateSyncRunItem :exec
+UPDATE sync_run_items SET status = ?, tidal_id = ?, reason = ?, updated_at = CURRENT_TIMESTAMP
+WHERE run_id = ? AND playlist_id = ? AND spotify_id = ?;
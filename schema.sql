@@ -3,7 +3,11 @@ CREATE TABLE IF NOT EXISTS tracks (
 );
 
 CREATE TABLE IF NOT EXISTS playlists (
-  id TEXT PRIMARY KEY
+  id TEXT PRIMARY KEY,
+  snapshot_id TEXT,
+  snapshot_options TEXT,
+  missing_tracks INTEGER NOT NULL DEFAULT 0,
+  synced_at TIMESTAMP
 );
 
 CREATE TABLE IF NOT EXISTS playlist_tracks (
@@ -14,3 +18,69 @@ CREATE TABLE IF NOT EXISTS playlist_tracks (
   FOREIGN KEY (playlist_id) REFERENCES playlists(id),
   FOREIGN KEY (track_id) REFERENCES tracks(id)
 );
+
+CREATE TABLE IF NOT EXISTS track_mappings (
+  spotify_id TEXT PRIMARY KEY,
+  isrc TEXT,
+  tidal_id TEXT NOT NULL,
+  match_method TEXT NOT NULL,
+  confidence REAL NOT NULL,
+  matched_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS track_mappings_isrc_idx ON track_mappings (isrc);
+
+CREATE INDEX IF NOT EXISTS track_mappings_tidal_id_idx ON track_mappings (tidal_id);
+
+CREATE TABLE IF NOT EXISTS library_mappings (
+  kind TEXT NOT NULL,
+  spotify_id TEXT NOT NULL,
+  tidal_id TEXT NOT NULL,
+  match_method TEXT NOT NULL,
+  confidence REAL NOT NULL,
+  matched_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (kind, spotify_id)
+);
+
+CREATE TABLE IF NOT EXISTS missing_tracks (
+  spotify_id TEXT PRIMARY KEY,
+  isrc TEXT,
+  attempts INTEGER NOT NULL DEFAULT 1,
+  last_checked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS track_overrides (
+  spotify_id TEXT PRIMARY KEY,
+  tidal_id TEXT,
+  ignored BOOLEAN NOT NULL DEFAULT 0,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS playlist_base_tracks (
+  playlist_id TEXT NOT NULL,
+  spotify_id TEXT NOT NULL,
+  tidal_id TEXT NOT NULL,
+  PRIMARY KEY (playlist_id, spotify_id),
+  FOREIGN KEY (playlist_id) REFERENCES playlists(id)
+);
+
+CREATE TABLE IF NOT EXISTS sync_runs (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  status TEXT NOT NULL,
+  started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  finished_at TIMESTAMP,
+  job TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS sync_run_items (
+  run_id INTEGER NOT NULL,
+  playlist_id TEXT NOT NULL,
+  spotify_id TEXT NOT NULL,
+  position INTEGER NOT NULL,
+  status TEXT NOT NULL,
+  tidal_id TEXT,
+  reason TEXT,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (run_id, playlist_id, spotify_id),
+  FOREIGN KEY (run_id) REFERENCES sync_runs(id)
+);
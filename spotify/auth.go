@@ -1,32 +1,64 @@
 package spotify
 
 import (
+	"bufio"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/zibbp/spotify-playlist-sync/config"
 	"github.com/zmb3/spotify/v2"
 	spotifyauth "github.com/zmb3/spotify/v2/auth"
+	"golang.org/x/exp/slices"
 	"golang.org/x/oauth2"
 )
 
 var (
-	ch    = make(chan *spotify.Client)
-	state = "music-utils"
+	// playlist modify scopes are required to sync Tidal playlists to Spotify
+	// library read is required to sync liked songs and saved albums, follow read to sync followed artists
+	scopes = []string{
+		spotifyauth.ScopeUserReadPrivate,
+		spotifyauth.ScopePlaylistReadPrivate,
+		spotifyauth.ScopePlaylistModifyPublic,
+		spotifyauth.ScopePlaylistModifyPrivate,
+		spotifyauth.ScopeUserLibraryRead,
+		spotifyauth.ScopeUserFollowRead,
+	}
 )
 
-func (s *Service) authFlow() (*spotify.Client, error) {
-	// Ensure Spotify application ID and secret are set
-	if s.clientId == "" || s.clientSecret == "" {
-		return nil, fmt.Errorf("spotify client ID and secret not provided")
+// authenticator returns the Spotify OAuth2 authenticator of the application.
+// The client secret is optional, without it PKCE is used to authorize and the tokens are refreshed with the client ID only.
+func (s *Service) authenticator() *spotifyauth.Authenticator {
+	return spotifyauth.New(spotifyauth.WithClientID(s.clientId), spotifyauth.WithClientSecret(s.clientSecret), spotifyauth.WithRedirectURL(s.clientRedirectUri), spotifyauth.WithScopes(scopes...))
+}
+
+// authFlow returns a client using the saved tokens.
+// If no account is logged in the user is prompted to log in, or ErrLoginRequired is returned if not interactive.
+func (s *Service) authFlow(ctx context.Context, interactive bool) (*spotify.Client, error) {
+	// Ensure Spotify application ID is set, the secret is optional with PKCE
+	if s.clientId == "" {
+		return nil, fmt.Errorf("spotify client ID not provided")
 	}
 
 	// Check if Spotify access and refresh token is set
 	// If set, fetch and return client
 	if s.config.Get().Spotify.AccessToken == "" || s.config.Get().Spotify.RefreshToken == "" {
-		log.Warn().Msg("Spotify access token and refresh token not set")
-		client, err := s.auth()
+		if !interactive {
+			return nil, fmt.Errorf("no Spotify account logged in, log in with `auth login spotify`: %w", ErrLoginRequired)
+		}
+
+		log.Warn().Msg("Spotify access token and refresh token not set, on a headless server run `auth login spotify --paste` instead")
+		client, err := s.Login(ctx, false)
 		if err != nil {
 			return nil, fmt.Errorf("error authenticating with Spotify: %w", err)
 		}
@@ -42,26 +74,31 @@ func (s *Service) authFlow() (*spotify.Client, error) {
 		Expiry:       s.config.Get().Spotify.Expiry,
 		TokenType:    s.config.Get().Spotify.TokenType,
 	}
-	spotClientID := s.clientId
-	spotClientSecret := s.clientSecret
-	redirectURI := s.clientRedirectUri
-	auth := spotifyauth.New(spotifyauth.WithClientID(spotClientID), spotifyauth.WithClientSecret(spotClientSecret), spotifyauth.WithRedirectURL(redirectURI), spotifyauth.WithScopes(spotifyauth.ScopeUserReadPrivate, spotifyauth.ScopePlaylistReadPrivate))
+	auth := s.authenticator()
 
-	client := spotify.New(auth.Client(context.Background(), tok))
+	client := spotify.New(auth.Client(ctx, tok))
 
-	newTok, _ := client.Token()
+	// the token is refreshed if it expired, which fails if it was revoked
+	newTok, err := client.Token()
+	if err != nil {
+		return nil, fmt.Errorf("error refreshing Spotify token, log in again with `auth login spotify`: %w", err)
+	}
 	c := s.config.Get()
 	c.Spotify.AccessToken = newTok.AccessToken
 	c.Spotify.RefreshToken = newTok.RefreshToken
 	c.Spotify.Expiry = newTok.Expiry
 	c.Spotify.TokenType = newTok.TokenType
+	// only refreshed tokens have the granted scopes
+	if scope, ok := newTok.Extra("scope").(string); ok && scope != "" {
+		c.Spotify.Scope = scope
+	}
 
-	err := s.config.Update(c)
+	err = s.config.Update(c)
 	if err != nil {
 		return nil, fmt.Errorf("error updating Spotify config: %w", err)
 	}
 
-	user, err := client.CurrentUser(context.Background())
+	user, err := client.CurrentUser(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error getting current user: %w", err)
 	}
@@ -70,66 +107,235 @@ func (s *Service) authFlow() (*spotify.Client, error) {
 	return client, nil
 }
 
-func (s *Service) auth() (*spotify.Client, error) {
-	spotClientID := s.clientId
-	spotClientSecret := s.clientSecret
-	redirectURI := s.clientRedirectUri
-	auth := spotifyauth.New(spotifyauth.WithClientID(spotClientID), spotifyauth.WithClientSecret(spotClientSecret), spotifyauth.WithRedirectURL(redirectURI), spotifyauth.WithScopes(spotifyauth.ScopeUserReadPrivate, spotifyauth.ScopePlaylistReadPrivate))
-	// Start an HTTP server
-	http.HandleFunc("/callback", s.completeAuth)
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
-	go func() {
-		err := http.ListenAndServe(":28542", nil)
+// Login authorizes the application with a Spotify account and saves the tokens to the config.
+// The redirect is received by a callback server listening on the redirect URI port.
+// If paste is set the user pastes the URL they were redirected to instead, for servers the browser cannot reach.
+func (s *Service) Login(ctx context.Context, paste bool) (*spotify.Client, error) {
+	if s.clientId == "" {
+		return nil, fmt.Errorf("spotify client ID not provided")
+	}
+
+	auth := s.authenticator()
+
+	state, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	// without a client secret the authorization code is bound to a PKCE code verifier
+	var authOpts, exchangeOpts []oauth2.AuthCodeOption
+	if s.clientSecret == "" {
+		verifier, err := randomString(64)
 		if err != nil {
-			log.Error().Msgf("Error starting HTTP server: %v", err)
+			return nil, err
 		}
-	}()
+		challenge := sha256.Sum256([]byte(verifier))
+		authOpts = append(authOpts,
+			oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+			oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+		)
+		exchangeOpts = append(exchangeOpts, oauth2.SetAuthURLParam("code_verifier", verifier))
+	}
 
-	url := auth.AuthURL(state)
-	log.Info().Msgf("Please log in to Spotify by visiting the following page in your browser: %s", url)
+	authURL := auth.AuthURL(state, authOpts...)
 
-	// wait for auth to complete
-	client := <-ch
+	var redirect *url.URL
+	if paste {
+		redirect, err = pasteRedirect(ctx, authURL)
+	} else {
+		redirect, err = s.callbackRedirect(ctx, authURL, state)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := authCode(redirect, state)
+	if err != nil {
+		return nil, err
+	}
+
+	tok, err := auth.Exchange(ctx, code, exchangeOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("error exchanging authorization code: %w", err)
+	}
+
+	// Save token to config
+	c := s.config.Get()
+	c.Spotify.AccessToken = tok.AccessToken
+	c.Spotify.RefreshToken = tok.RefreshToken
+	c.Spotify.Expiry = tok.Expiry
+	c.Spotify.TokenType = tok.TokenType
+	c.Spotify.Scope, _ = tok.Extra("scope").(string)
 
-	// use the client to make calls that require authorization
-	user, err := client.CurrentUser(context.Background())
+	if err := s.config.Update(c); err != nil {
+		return nil, fmt.Errorf("error updating Spotify config: %w", err)
+	}
+
+	// use the token to get an authenticated client
+	client := spotify.New(auth.Client(ctx, tok))
+
+	user, err := client.CurrentUser(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error getting current user: %w", err)
 	}
 	log.Info().Msgf("Spotify - logged in as: %s", user.ID)
+
 	return client, nil
 }
 
-func (s *Service) completeAuth(w http.ResponseWriter, r *http.Request) {
-	spotClientID := s.clientId
-	spotClientSecret := s.clientSecret
-	redirectURI := s.clientRedirectUri
-	auth := spotifyauth.New(spotifyauth.WithClientID(spotClientID), spotifyauth.WithClientSecret(spotClientSecret), spotifyauth.WithRedirectURL(redirectURI), spotifyauth.WithScopes(spotifyauth.ScopeUserReadPrivate, spotifyauth.ScopePlaylistReadPrivate))
+// callbackRedirect serves the redirect URI until Spotify redirects the browser to it and returns the redirect URL.
+func (s *Service) callbackRedirect(ctx context.Context, authURL string, state string) (*url.URL, error) {
+	redirectURI, err := url.Parse(s.clientRedirectUri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Spotify redirect URI: %w", err)
+	}
+	port := redirectURI.Port()
+	if port == "" {
+		port = "80"
+	}
 
-	tok, err := auth.Token(r.Context(), state, r)
+	// listen before printing the URL so a port that is in use fails immediately
+	listener, err := net.Listen("tcp", ":"+port)
 	if err != nil {
-		http.Error(w, "Couldn't get token", http.StatusForbidden)
-		log.Error().Msgf("Couldn't get token: %v", err)
+		return nil, fmt.Errorf("error starting callback server, use `auth login spotify --paste` instead: %w", err)
 	}
-	if st := r.FormValue("state"); st != state {
-		http.NotFound(w, r)
-		log.Error().Msgf("State mismatch: %s != %s\n", st, state)
+
+	redirects := make(chan *url.URL, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc(redirectURI.Path, func(w http.ResponseWriter, r *http.Request) {
+		// ignore stale redirects of earlier logins
+		if r.URL.Query().Get("state") != state {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, "Logged in to Spotify, you can close this page.")
+		select {
+		case redirects <- r.URL:
+		default:
+		}
+	})
+	server := &http.Server{Handler: mux}
+	defer func() {
+		// let the browser receive the response before stopping
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Msgf("Error starting HTTP server: %v", err)
+		}
+	}()
+
+	log.Info().Msgf("Please log in to Spotify by visiting the following page in your browser: %s", authURL)
+
+	// wait for auth to complete
+	select {
+	case redirect := <-redirects:
+		return redirect, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
+}
 
-	// Save token to config
+// pasteRedirect asks the user to paste the URL their browser was redirected to after logging in.
+func pasteRedirect(ctx context.Context, authURL string) (*url.URL, error) {
+	log.Info().Msgf("Please log in to Spotify by visiting the following page in your browser: %s", authURL)
+	log.Info().Msg("After logging in your browser is redirected to a page that may fail to load, copy the full URL from the address bar")
+	fmt.Fprint(os.Stderr, "Paste the redirect URL: ")
 
-	c := s.config.Get()
-	c.Spotify.AccessToken = tok.AccessToken
-	c.Spotify.RefreshToken = tok.RefreshToken
-	c.Spotify.Expiry = tok.Expiry
-	c.Spotify.TokenType = tok.TokenType
+	lines := make(chan string, 1)
+	errs := make(chan error, 1)
+	go func() {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			errs <- fmt.Errorf("error reading redirect URL: %w", err)
+			return
+		}
+		lines <- line
+	}()
 
-	err = s.config.Update(c)
+	select {
+	case line := <-lines:
+		redirect, err := url.Parse(strings.TrimSpace(line))
+		if err != nil {
+			return nil, fmt.Errorf("invalid redirect URL: %w", err)
+		}
+		return redirect, nil
+	case err := <-errs:
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// authCode returns the authorization code of the redirect URL.
+func authCode(redirect *url.URL, state string) (string, error) {
+	values := redirect.Query()
+	if e := values.Get("error"); e != "" {
+		return "", fmt.Errorf("spotify authorization failed: %s", e)
+	}
+	if values.Get("state") != state {
+		return "", fmt.Errorf("redirect state does not match, use the URL of the latest login")
+	}
+	code := values.Get("code")
+	if code == "" {
+		return "", fmt.Errorf("redirect URL does not contain an authorization code")
+	}
+	return code, nil
+}
+
+// randomString returns a random URL safe string of the length, used for the OAuth2 state and PKCE code verifier.
+func randomString(length int) (string, error) {
+	b := make([]byte, length)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b)[:length], nil
+}
+
+// AuthStatus describes the Spotify account that is logged in.
+type AuthStatus struct {
+	UserID        string
+	Expiry        time.Time
+	Scopes        []string
+	MissingScopes []string // required scopes that were not granted, log in again to grant them
+}
+
+// Status checks the saved tokens without prompting to log in, refreshing the access token if it expired.
+func (s *Service) Status(ctx context.Context) (*AuthStatus, error) {
+	client, err := s.authFlow(ctx, false)
 	if err != nil {
-		log.Error().Err(err).Msg("Error updating Spotify config")
+		return nil, err
 	}
 
-	// use the token to get an authenticated client
-	client := spotify.New(auth.Client(r.Context(), tok))
-	ch <- client
+	user, err := client.CurrentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting current user: %w", err)
+	}
+
+	c := s.config.Get()
+	status := &AuthStatus{
+		UserID: user.ID,
+		Expiry: c.Spotify.Expiry,
+		Scopes: strings.Fields(c.Spotify.Scope),
+	}
+	// tokens saved by earlier versions do not have their scopes
+	if len(status.Scopes) > 0 {
+		for _, scope := range scopes {
+			if !slices.Contains(status.Scopes, scope) {
+				status.MissingScopes = append(status.MissingScopes, scope)
+			}
+		}
+	}
+
+	return status, nil
+}
+
+// Logout removes the saved Spotify tokens.
+func Logout(jsonConfig *config.JsonConfigService) error {
+	c := jsonConfig.Get()
+	c.Spotify = config.SpotifyConfig{}
+	return jsonConfig.Update(c)
 }
@@ -2,12 +2,21 @@ package spotify
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/zibbp/spotify-playlist-sync/config"
 
 	spotifyPkg "github.com/zmb3/spotify/v2"
 )
 
+const (
+	// maximum number of tracks added to or removed from a playlist in a single request
+	addTracksChunkSize = 100
+	// number of search results that are scored when matching a track
+	maxSearchResults = 10
+)
+
 type Service struct {
 	client            *spotifyPkg.Client
 	config            *config.JsonConfigService
@@ -28,8 +37,12 @@ func Initialize(clientId, clientSecret, clientRedirectUri string, config *config
 	return &s, nil
 }
 
-func (s *Service) Authenticate() error {
-	client, err := s.authFlow()
+// ErrLoginRequired is returned when no account is logged in and prompting to log in is disabled.
+var ErrLoginRequired = errors.New("login required")
+
+// Authenticate creates the client from the saved tokens, prompting to log in if none are saved and interactive is set.
+func (s *Service) Authenticate(ctx context.Context, interactive bool) error {
+	client, err := s.authFlow(ctx, interactive)
 	if err != nil {
 		return err
 	}
@@ -39,8 +52,8 @@ func (s *Service) Authenticate() error {
 	return nil
 }
 
-func (s *Service) GetUserPlaylists() ([]spotifyPkg.SimplePlaylist, error) {
-	playlists, err := s.client.CurrentUsersPlaylists(context.Background())
+func (s *Service) GetUserPlaylists(ctx context.Context) ([]spotifyPkg.SimplePlaylist, error) {
+	playlists, err := s.client.CurrentUsersPlaylists(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -52,7 +65,7 @@ func (s *Service) GetUserPlaylists() ([]spotifyPkg.SimplePlaylist, error) {
 			break
 		}
 
-		err = s.client.NextPage(context.Background(), playlists)
+		err = s.client.NextPage(ctx, playlists)
 		if err != nil {
 			return nil, err
 		}
@@ -61,8 +74,8 @@ func (s *Service) GetUserPlaylists() ([]spotifyPkg.SimplePlaylist, error) {
 	return allPlaylists, nil
 }
 
-func (s *Service) GetPlaylistTracks(id spotifyPkg.ID) ([]*spotifyPkg.FullTrack, error) {
-	items, err := s.client.GetPlaylistItems(context.Background(), id)
+func (s *Service) GetPlaylistTracks(ctx context.Context, id spotifyPkg.ID) ([]*spotifyPkg.FullTrack, error) {
+	items, err := s.client.GetPlaylistItems(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +89,7 @@ func (s *Service) GetPlaylistTracks(id spotifyPkg.ID) ([]*spotifyPkg.FullTrack,
 			break
 		}
 
-		err = s.client.NextPage(context.Background(), items)
+		err = s.client.NextPage(ctx, items)
 		if err != nil {
 			return nil, err
 		}
@@ -84,3 +97,150 @@ func (s *Service) GetPlaylistTracks(id spotifyPkg.ID) ([]*spotifyPkg.FullTrack,
 
 	return allTracks, nil
 }
+
+// GetLikedTracks returns the tracks in the user's Liked Songs.
+func (s *Service) GetLikedTracks(ctx context.Context) ([]*spotifyPkg.FullTrack, error) {
+	items, err := s.client.CurrentUsersTracks(ctx, spotifyPkg.Limit(50))
+	if err != nil {
+		return nil, err
+	}
+
+	var allTracks []*spotifyPkg.FullTrack
+	for page := 1; ; page++ {
+		for i := range items.Tracks {
+			allTracks = append(allTracks, &items.Tracks[i].FullTrack)
+		}
+		if items.Next == "" {
+			break
+		}
+
+		err = s.client.NextPage(ctx, items)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return allTracks, nil
+}
+
+// GetSavedAlbums returns the albums saved in the user's library.
+func (s *Service) GetSavedAlbums(ctx context.Context) ([]spotifyPkg.FullAlbum, error) {
+	items, err := s.client.CurrentUsersAlbums(ctx, spotifyPkg.Limit(50))
+	if err != nil {
+		return nil, err
+	}
+
+	var allAlbums []spotifyPkg.FullAlbum
+	for page := 1; ; page++ {
+		for _, album := range items.Albums {
+			allAlbums = append(allAlbums, album.FullAlbum)
+		}
+		if items.Next == "" {
+			break
+		}
+
+		err = s.client.NextPage(ctx, items)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return allAlbums, nil
+}
+
+// GetFollowedArtists returns the artists the user follows.
+func (s *Service) GetFollowedArtists(ctx context.Context) ([]spotifyPkg.FullArtist, error) {
+	var allArtists []spotifyPkg.FullArtist
+	opts := []spotifyPkg.RequestOption{spotifyPkg.Limit(50)}
+	for {
+		items, err := s.client.CurrentUsersFollowedArtists(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		allArtists = append(allArtists, items.Artists...)
+		if items.Next == "" || len(items.Artists) == 0 {
+			break
+		}
+
+		// followed artists are paged with a cursor of the last artist id
+		opts = []spotifyPkg.RequestOption{spotifyPkg.Limit(50), spotifyPkg.After(items.Artists[len(items.Artists)-1].ID.String())}
+	}
+
+	return allArtists, nil
+}
+
+// CreatePlaylist creates a private playlist for the current user.
+func (s *Service) CreatePlaylist(ctx context.Context, name, description string) (*spotifyPkg.FullPlaylist, error) {
+	user, err := s.client.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.client.CreatePlaylistForUser(ctx, user.ID, name, description, false, false)
+}
+
+// UpdatePlaylist sets the name and description of the playlist.
+func (s *Service) UpdatePlaylist(ctx context.Context, id spotifyPkg.ID, name, description string) error {
+	if err := s.client.ChangePlaylistName(ctx, id, name); err != nil {
+		return err
+	}
+
+	return s.client.ChangePlaylistDescription(ctx, id, description)
+}
+
+// AddTracksToPlaylist appends the tracks to the playlist in chunks of the maximum Spotify allows per request.
+func (s *Service) AddTracksToPlaylist(ctx context.Context, id spotifyPkg.ID, trackIDs []spotifyPkg.ID) error {
+	for start := 0; start < len(trackIDs); start += addTracksChunkSize {
+		end := start + addTracksChunkSize
+		if end > len(trackIDs) {
+			end = len(trackIDs)
+		}
+
+		if _, err := s.client.AddTracksToPlaylist(ctx, id, trackIDs[start:end]...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveTracksFromPlaylist removes every occurrence of the tracks from the playlist.
+func (s *Service) RemoveTracksFromPlaylist(ctx context.Context, id spotifyPkg.ID, trackIDs []spotifyPkg.ID) error {
+	for start := 0; start < len(trackIDs); start += addTracksChunkSize {
+		end := start + addTracksChunkSize
+		if end > len(trackIDs) {
+			end = len(trackIDs)
+		}
+
+		if _, err := s.client.RemoveTracksFromPlaylist(ctx, id, trackIDs[start:end]...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetArtistTopTracks returns the most popular tracks of the artist in the country.
+func (s *Service) GetArtistTopTracks(ctx context.Context, id spotifyPkg.ID, country string) ([]spotifyPkg.FullTrack, error) {
+	return s.client.GetArtistsTopTracks(ctx, id, country)
+}
+
+// SearchTrackByISRC returns the tracks with the ISRC.
+func (s *Service) SearchTrackByISRC(ctx context.Context, isrc string) ([]spotifyPkg.FullTrack, error) {
+	return s.SearchTracks(ctx, fmt.Sprintf("isrc:%s", isrc))
+}
+
+// SearchTracks returns the top track results for the query.
+func (s *Service) SearchTracks(ctx context.Context, query string) ([]spotifyPkg.FullTrack, error) {
+	results, err := s.client.Search(ctx, query, spotifyPkg.SearchTypeTrack, spotifyPkg.Limit(maxSearchResults))
+	if err != nil {
+		return nil, err
+	}
+
+	if results.Tracks == nil {
+		return nil, nil
+	}
+
+	return results.Tracks.Tracks, nil
+}
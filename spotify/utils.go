@@ -12,6 +12,18 @@ import (
 type MissingTracks struct {
 	Playlist libSpotify.SimplePlaylist `json:"playlist"`
 	Tracks   []*libSpotify.FullTrack   `json:"tracks"`
+	// Reasons is why each track was not found, keyed by Spotify track ID
+	Reasons map[string]string `json:"reasons,omitempty"`
+	// Conflicts are the tracks that could not be merged when syncing in both directions
+	Conflicts []TrackConflict `json:"conflicts,omitempty"`
+}
+
+// TrackConflict is a track that could not be merged cleanly between a linked Spotify and Tidal playlist.
+type TrackConflict struct {
+	SpotifyID string `json:"spotify_id,omitempty"`
+	TidalID   string `json:"tidal_id,omitempty"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason"`
 }
 
 // WriteMissingTracks writes missing tracks Spotify playlist tracks to disk
@@ -25,3 +37,39 @@ func WriteMissingTracks(filename string, missingTracks MissingTracks, config con
 	}
 	return os.WriteFile(fmt.Sprintf(config.DataPath+"/missing/%s.json", filename), json, 0644)
 }
+
+type MissingAlbums struct {
+	Albums []libSpotify.FullAlbum `json:"albums"`
+	// Reasons is why each album was not found, keyed by Spotify album ID
+	Reasons map[string]string `json:"reasons,omitempty"`
+}
+
+type MissingArtists struct {
+	Artists []libSpotify.FullArtist `json:"artists"`
+	// Reasons is why each artist was not found, keyed by Spotify artist ID
+	Reasons map[string]string `json:"reasons,omitempty"`
+}
+
+// WriteMissingAlbums writes saved albums that were not found to disk
+func WriteMissingAlbums(missingAlbums MissingAlbums, config config.Config) error {
+	if err := os.MkdirAll(config.DataPath+"/missing", 0755); err != nil {
+		return err
+	}
+	json, err := json.Marshal(missingAlbums)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(config.DataPath+"/missing/albums.json", json, 0644)
+}
+
+// WriteMissingArtists writes followed artists that were not found to disk
+func WriteMissingArtists(missingArtists MissingArtists, config config.Config) error {
+	if err := os.MkdirAll(config.DataPath+"/missing", 0755); err != nil {
+		return err
+	}
+	json, err := json.Marshal(missingArtists)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(config.DataPath+"/missing/artists.json", json, 0644)
+}
@@ -0,0 +1,165 @@
+package tidal
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"golang.org/x/time/rate"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Rate limit of each Tidal host: Allow 5 requests per second with bursts of 2
+const (
+	hostRateLimit = 5
+	hostRateBurst = 2
+)
+
+// hostLimiter is shared by all requests to a single Tidal host so concurrent requests respect the same limit.
+type hostLimiter struct {
+	limiter *rate.Limiter
+
+	// all requests to the host are paused until this time after Tidal responds with Retry-After
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+var (
+	hostLimitersMu sync.Mutex
+	hostLimiters   = make(map[string]*hostLimiter)
+)
+
+// limiterFor returns the limiter of the host, creating it on first use.
+func limiterFor(host string) *hostLimiter {
+	hostLimitersMu.Lock()
+	defer hostLimitersMu.Unlock()
+
+	l, ok := hostLimiters[host]
+	if !ok {
+		l = &hostLimiter{limiter: rate.NewLimiter(hostRateLimit, hostRateBurst)}
+		hostLimiters[host] = l
+	}
+	return l
+}
+
+// pause delays every request to the host, not only the one that was rate limited.
+func (l *hostLimiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// wait waits until requests to the host are no longer paused and a rate limit token is available.
+func (l *hostLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	delay := time.Until(l.pausedUntil)
+	l.mu.Unlock()
+
+	if delay > 0 {
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+
+	return l.limiter.Wait(ctx) // Wait for rate limit token, or until the request is cancelled
+}
+
+// rateLimitedTransport waits for the limiter of the request host before sending each request, including retries.
+type rateLimitedTransport struct {
+	base http.RoundTripper
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := limiterFor(req.URL.Host).wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// requestMethodKey is the context key of the request method, the retry policy only receives the request context.
+type requestMethodKey struct{}
+
+// methodTransport stores the request method in the request context for the retry policy.
+type methodTransport struct {
+	base http.RoundTripper
+}
+
+func (t *methodTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(context.WithValue(req.Context(), requestMethodKey{}, req.Method)))
+}
+
+// retryable reports whether the request can be sent again after it failed without knowing if Tidal handled it.
+// Only requests that read are retried, e.g. a playlist may have been created before the connection was lost
+// and removing playlist items by index twice removes other tracks.
+func retryable(ctx context.Context) bool {
+	switch ctx.Value(requestMethodKey{}) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// Custom retry policy to handle 429 Too Many Requests
+func retryPolicy(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	// do not retry when the request was cancelled
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	if err != nil {
+		return retryable(ctx), err // Retry reads on network errors
+	}
+	if resp.StatusCode == http.StatusTooManyRequests { // Handle 429 Too Many Requests
+		log.Info().Str("host", resp.Request.URL.Host).Msg("rate limited by Tidal API. waiting before retrying.")
+		delay := 3 * time.Second
+		retryAfter := resp.Header.Get("Retry-After")
+		if retryAfter != "" {
+			if seconds, err := strconv.Atoi(retryAfter); err == nil {
+				delay = time.Duration(seconds) * time.Second // Wait based on Retry-After header
+			}
+		}
+		// the retry and all other requests to the host wait for the pause in the transport
+		limiterFor(resp.Request.URL.Host).pause(delay)
+		return true, nil
+	}
+	if resp.StatusCode >= 500 { // Retry reads on 5xx errors
+		return retryable(ctx), nil
+	}
+	return false, nil
+}
+
+// newHTTPClient returns the client used for every Tidal request, including authentication and the unofficial API.
+// Requests are rate limited per host and retried on 429 responses. Reads are also retried on network errors and 5xx responses.
+func newHTTPClient() *http.Client {
+	retryClient := retryablehttp.NewClient()
+	retryClient.RetryMax = 5                          // Maximum retry attempts
+	retryClient.RetryWaitMin = 500 * time.Millisecond // Minimum wait before retry
+	retryClient.RetryWaitMax = 2 * time.Second        // Maximum wait before retry
+	retryClient.CheckRetry = retryPolicy
+	retryClient.Logger = nil // Disable logging
+	retryClient.HTTPClient.Transport = &rateLimitedTransport{base: retryClient.HTTPClient.Transport}
+	// return the last response once retries are exhausted so callers get an APIError with the status
+	retryClient.ErrorHandler = retryablehttp.PassthroughErrorHandler
+
+	// Convert retryablehttp.Client to standard http.Client
+	client := retryClient.StandardClient()
+	client.Transport = &methodTransport{base: client.Transport}
+	return client
+}
+
+// sleepContext waits for the duration or until the context is cancelled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -1,56 +1,60 @@
 package tidal
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
-func (s *Service) clientAuth(clientId string, clientSecret string) (string, error) {
-
-	client := &http.Client{}
+// clientAuth requests a client-credentials access token, returning it with its expiry.
+func (s *Service) clientAuth(ctx context.Context, clientId string, clientSecret string) (string, time.Time, error) {
 
 	params := url.Values{}
 	params.Set("grant_type", "client_credentials")
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/token", authURL), strings.NewReader(params.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/token", authURL), strings.NewReader(params.Encode()))
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
 	req.SetBasicAuth(clientId, clientSecret)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := client.Do(req)
+	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("failed to authenticate client: %s", resp.Status)
+		return "", time.Time{}, newAPIError("authenticate client", resp, nil)
 	}
 
 	// parse json response without struct
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
 	var result map[string]interface{}
 	err = json.Unmarshal(body, &result)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
 	accessToken, ok := result["access_token"].(string)
 	if !ok {
-		return "", fmt.Errorf("failed to parse access token")
+		return "", time.Time{}, fmt.Errorf("failed to parse access token")
 	}
 
-	return accessToken, nil
+	// expires_in is a JSON number, which is decoded as float64
+	expiresIn, _ := result["expires_in"].(float64)
+
+	return accessToken, expiryIn(int64(expiresIn)), nil
 }
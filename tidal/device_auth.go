@@ -3,6 +3,7 @@
 package tidal
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -37,6 +38,7 @@ type AuthLogin struct {
 	RefreshToken string `json:"refresh_token"`
 	TokenType    string `json:"token_type"`
 	ExpiresIn    int64  `json:"expires_in"`
+	Scope        string `json:"scope"`
 	User         User   `json:"user"`
 }
 
@@ -44,6 +46,7 @@ type Refresh struct {
 	AccessToken string `json:"access_token"`
 	TokenType   string `json:"token_type"`
 	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
 	User        User   `json:"user"`
 }
 
@@ -98,25 +101,23 @@ type Client struct {
 	AuthorizedForOfflineDate interface{} `json:"authorizedForOfflineDate"`
 }
 
-func (s *Service) getDeviceCode() (*DeviceCode, error) {
+func (s *Service) getDeviceCode(ctx context.Context) (*DeviceCode, error) {
 	var deviceCode DeviceCode
 
-	client := &http.Client{}
-
 	data := url.Values{}
 	data.Set("client_id", clientId)
 	data.Set("scope", "r_usr+w_usr+w_sub")
 
 	encodedData := data.Encode()
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/device_authorization", authURL), strings.NewReader(encodedData))
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/device_authorization", authURL), strings.NewReader(encodedData))
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := client.Do(req)
+	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -129,7 +130,7 @@ func (s *Service) getDeviceCode() (*DeviceCode, error) {
 	}
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("failed to get device code: %s", resp.Status)
+		return nil, newAPIError("get device code", resp, nil)
 	}
 
 	err = json.Unmarshal(body, &deviceCode)
@@ -140,10 +141,9 @@ func (s *Service) getDeviceCode() (*DeviceCode, error) {
 	return &deviceCode, nil
 }
 
-func (s *Service) tokenLogin(deviceCode DeviceCode) (*LoginResponse, error) {
+func (s *Service) tokenLogin(ctx context.Context, deviceCode DeviceCode) (*LoginResponse, error) {
 	var loginResponse LoginResponse
 
-	client := &http.Client{}
 	// Set body
 	data := url.Values{}
 	data.Set("client_id", clientId)
@@ -153,7 +153,7 @@ func (s *Service) tokenLogin(deviceCode DeviceCode) (*LoginResponse, error) {
 
 	encodedData := data.Encode()
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/token", authURL), strings.NewReader(encodedData))
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/token", authURL), strings.NewReader(encodedData))
 	if err != nil {
 		return nil, err
 	}
@@ -163,7 +163,7 @@ func (s *Service) tokenLogin(deviceCode DeviceCode) (*LoginResponse, error) {
 	// Set Headers
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := client.Do(req)
+	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -195,19 +195,17 @@ func (s *Service) tokenLogin(deviceCode DeviceCode) (*LoginResponse, error) {
 	return &loginResponse, nil
 }
 
-func (s *Service) checkSession(accessToken string) (Session, error) {
+func (s *Service) checkSession(ctx context.Context, accessToken string) (Session, error) {
 	var session Session
 
-	client := &http.Client{}
-
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/sessions", apiURL), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/sessions", apiURL), nil)
 	if err != nil {
 		return session, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	resp, err := client.Do(req)
+	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		return session, err
 	}
@@ -220,7 +218,7 @@ func (s *Service) checkSession(accessToken string) (Session, error) {
 	}
 
 	if resp.StatusCode != 200 {
-		return session, fmt.Errorf("failed to check session: %s", resp.Status)
+		return session, newAPIError("check session", resp, nil)
 	}
 
 	err = json.Unmarshal(body, &session)
@@ -231,11 +229,9 @@ func (s *Service) checkSession(accessToken string) (Session, error) {
 	return session, nil
 }
 
-func (s *Service) refreshSession(refreshToken string) (*Refresh, error) {
+func (s *Service) refreshSession(ctx context.Context, refreshToken string) (*Refresh, error) {
 	var refresh Refresh
 
-	client := &http.Client{}
-
 	data := url.Values{}
 	data.Set("client_id", clientId)
 	data.Set("refresh_token", refreshToken)
@@ -244,7 +240,7 @@ func (s *Service) refreshSession(refreshToken string) (*Refresh, error) {
 
 	encodedData := data.Encode()
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/token", authURL), strings.NewReader(encodedData))
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/token", authURL), strings.NewReader(encodedData))
 	if err != nil {
 		return nil, err
 	}
@@ -253,7 +249,7 @@ func (s *Service) refreshSession(refreshToken string) (*Refresh, error) {
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := client.Do(req)
+	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -266,7 +262,7 @@ func (s *Service) refreshSession(refreshToken string) (*Refresh, error) {
 	}
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("failed to refresh session: %s", resp.Status)
+		return nil, newAPIError("refresh session", resp, nil)
 	}
 
 	err = json.Unmarshal(body, &refresh)
@@ -0,0 +1,62 @@
+package tidal
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrNotFound is returned when the requested resource does not exist, e.g. no track has the ISRC.
+	ErrNotFound = errors.New("not found")
+	// ErrUnauthorized is returned when the access token is missing, expired, or revoked.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrRateLimited is returned when requests are still rate limited after every retry.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrLoginRequired is returned when no account is logged in and prompting to log in is disabled.
+	ErrLoginRequired = errors.New("login required")
+)
+
+// APIError is returned when a Tidal request fails with an unexpected status code.
+// Use errors.Is with ErrNotFound, ErrUnauthorized, or ErrRateLimited to check for common failures.
+type APIError struct {
+	// Op is the request that failed, e.g. "create playlist"
+	Op         string
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("failed to %s: %s", e.Op, e.Status)
+	}
+	return fmt.Sprintf("failed to %s: %s: %s", e.Op, e.Status, e.Body)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// newAPIError returns the error of a response with an unexpected status code or content type.
+func newAPIError(op string, resp *http.Response, body []byte) *APIError {
+	status := resp.Status
+	// successful responses only fail if their body could not be decoded
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		status = fmt.Sprintf("%s with unexpected content type %q", resp.Status, resp.Header.Get("Content-Type"))
+	}
+	return &APIError{
+		Op:         op,
+		StatusCode: resp.StatusCode,
+		Status:     status,
+		Body:       string(body),
+	}
+}
@@ -2,17 +2,16 @@ package tidal
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/hashicorp/go-retryablehttp"
-	"github.com/oapi-codegen/oapi-codegen/v2/pkg/securityprovider"
 	"github.com/zibbp/spotify-playlist-sync/config"
 	tidal_search "github.com/zibbp/spotify-playlist-sync/tidal/search"
 	tidal_tracks "github.com/zibbp/spotify-playlist-sync/tidal/tracks"
-	"golang.org/x/time/rate"
 
 	"github.com/rs/zerolog/log"
 )
@@ -28,91 +27,44 @@ var (
 )
 
 type Service struct {
-	ClientId          string
-	ClientSecret      string
-	AccessToken       string // device-flow user resources access token
-	ClientAccessToken string // application client for accessing Tidal API non-user resources
-	UserID            string
-	Config            *config.JsonConfigService
-	TracksApiClient   *tidal_tracks.ClientWithResponses
-	SearchApiClient   *tidal_search.ClientWithResponses
-}
-
-// Rate limiter: Allow 5 requests per second with bursts of 2
-var limiter = rate.NewLimiter(5, 2)
-
-// Wraps an HTTP request with rate limiting
-func rateLimitedDo(client *http.Client, req *http.Request) (*http.Response, error) {
-	err := limiter.Wait(context.Background()) // Wait for rate limit token
-	if err != nil {
-		return nil, err
-	}
-	return client.Do(req)
-}
-
-// Custom retry policy to handle 429 Too Many Requests
-func retryPolicy(_ context.Context, resp *http.Response, err error) (bool, error) {
-	if err != nil {
-		return true, err // Retry on network errors
-	}
-	if resp.StatusCode == 429 { // Handle 429 Too Many Requests
-		log.Info().Msg("rate limited by Tidal API. waiting before retrying.")
-		retryAfter := resp.Header.Get("Retry-After")
-		if retryAfter != "" {
-			if delay, err := strconv.Atoi(retryAfter); err == nil {
-				time.Sleep(time.Duration(delay) * time.Second) // Wait based on Retry-After header
-			}
-		} else {
-			time.Sleep(3 * time.Second)
-		}
-		return true, nil
-	}
-	if resp.StatusCode >= 500 { // Retry on 5xx errors
-		return true, nil
-	}
-	return false, nil
+	ClientId     string
+	ClientSecret string
+	UserID       string
+	// CountryCode is the catalog country sent with every unofficial API request, e.g. when adding playlist items
+	CountryCode     string
+	Config          *config.JsonConfigService
+	TracksApiClient *tidal_tracks.ClientWithResponses
+	SearchApiClient *tidal_search.ClientWithResponses
+	httpClient      *http.Client // shared by every request, see newHTTPClient
+	userToken       *tokenSource // device-flow user resources access token
+	clientToken     *tokenSource // application client for accessing Tidal API non-user resources
+	userClient      *http.Client // authenticated with userToken for the unofficial API
 }
 
-func Initialize(clientId, clientSecret string, config *config.JsonConfigService) (*Service, error) {
+func Initialize(ctx context.Context, clientId, clientSecret, countryCode string, config *config.JsonConfigService) (*Service, error) {
 	var s Service
 	s.ClientId = clientId
 	s.ClientSecret = clientSecret
+	s.CountryCode = countryCode
 	s.Config = config
+	s.httpClient = newHTTPClient()
 
-	if s.Config.Get().Tidal.AccessToken != "" {
-		s.AccessToken = s.Config.Get().Tidal.AccessToken
-	}
+	s.userToken = newTokenSource(s.refreshUserToken)
+	s.userClient = newAuthClient(s.httpClient, s.userToken)
 
 	// client auth is non-interactive so run always
-	clientAccessToken, err := s.clientAuth(s.ClientId, s.ClientSecret)
-	if err != nil {
+	s.clientToken = newTokenSource(s.refreshClientToken)
+	if _, err := s.clientToken.Token(ctx); err != nil {
 		return nil, err
 	}
+	openAPIClient := newAuthClient(s.httpClient, s.clientToken)
 
-	s.ClientAccessToken = clientAccessToken
-
-	bearerTokenAuth, err := securityprovider.NewSecurityProviderBearerToken(s.ClientAccessToken)
+	apiClient, err := tidal_tracks.NewClientWithResponses(openAPIv2URL, tidal_tracks.WithHTTPClient(openAPIClient))
 	if err != nil {
 		return nil, err
 	}
 
-	// Configure retryable HTTP client
-	retryClient := retryablehttp.NewClient()
-	retryClient.RetryMax = 5                          // Maximum retry attempts
-	retryClient.RetryWaitMin = 500 * time.Millisecond // Minimum wait before retry
-	retryClient.RetryWaitMax = 2 * time.Second        // Maximum wait before retry
-	retryClient.CheckRetry = retryPolicy
-	retryClient.Logger = nil // Disable logging
-
-	// Convert retryablehttp.Client to standard http.Client
-	client := retryClient.StandardClient()
-
-	apiClient, err := tidal_tracks.NewClientWithResponses(openAPIv2URL, tidal_tracks.WithHTTPClient(client), tidal_tracks.WithRequestEditorFn(bearerTokenAuth.Intercept))
-	if err != nil {
-		return nil, err
-	}
-
-	searchApiClient, err := tidal_search.NewClientWithResponses(openAPIv2URL, tidal_search.WithHTTPClient(client), tidal_search.WithRequestEditorFn(bearerTokenAuth.Intercept))
+	searchApiClient, err := tidal_search.NewClientWithResponses(openAPIv2URL, tidal_search.WithHTTPClient(openAPIClient))
 
 	s.TracksApiClient = apiClient
 	s.SearchApiClient = searchApiClient
@@ -122,129 +74,444 @@ func Initialize(clientId, clientSecret string, config *config.JsonConfigService)
 
 // Perform device authentication with Tidal to access user resources
 // Once the official Tidal API supports user authentication, this method will be updated
-func (s *Service) DeviceAuthenticate() error {
+// If no account is logged in the device flow is started, or ErrLoginRequired is returned if not interactive.
+func (s *Service) DeviceAuthenticate(ctx context.Context, interactive bool) error {
 	if s.Config.Get().Tidal.AccessToken == "" || s.Config.Get().Tidal.RefreshToken == "" {
 		log.Debug().Msg("No Tidal access token found")
+		if !interactive {
+			return fmt.Errorf("no Tidal account logged in, log in with `auth login tidal`: %w", ErrLoginRequired)
+		}
 
-		deviceCode, err := s.getDeviceCode()
-		if err != nil {
+		if err := s.Login(ctx); err != nil {
 			return err
 		}
+	} else {
+		log.Debug().Msg("Tidal access token found")
+		_, err := s.checkSession(ctx, s.Config.Get().Tidal.AccessToken)
+		if err != nil {
+			// failed probably need to refresh
+			log.Debug().Msg("Tidal access token expired")
+			if _, _, err := s.refreshUserToken(ctx); err != nil {
+				return fmt.Errorf("error refreshing Tidal access token, log in again with `auth login tidal`: %w", err)
+			}
+		}
 
-		log.Info().Msgf("Please visit the following URL to authorize this application: https://%v", deviceCode.VerificationURIComplete)
+		log.Debug().Msg("Tidal access token valid")
+	}
 
-		// start poll for authorization
-		for {
-			loginResponse, err := s.tokenLogin(*deviceCode)
-			if err != nil {
-				// continue polling
-				log.Debug().Msg("Failed to login with Tidal")
+	s.userToken.set(s.Config.Get().Tidal.AccessToken, s.Config.Get().Tidal.Expiry)
+	s.UserID = s.Config.Get().Tidal.UserID
 
-			}
+	return nil
+}
 
-			if (loginResponse != nil && AuthLogin{} == loginResponse.AuthLogin) {
-				if loginResponse.AuthError.Error == "expired_token" {
-					log.Fatal().Msg("Tidal auth failed - device code expired")
-				}
-			} else {
-				s.Config.JsonConfig.Tidal.UserID = strconv.Itoa(int(loginResponse.AuthLogin.User.UserID))
-				s.Config.JsonConfig.Tidal.AccessToken = loginResponse.AuthLogin.AccessToken
-				s.Config.JsonConfig.Tidal.RefreshToken = loginResponse.AuthLogin.RefreshToken
-				s.Config.Save()
-				break
-			}
+// Login authorizes the application with a Tidal account using the device flow and saves the tokens to the config.
+// Any account that is already logged in is replaced.
+func (s *Service) Login(ctx context.Context) error {
+	deviceCode, err := s.getDeviceCode(ctx)
+	if err != nil {
+		return err
+	}
 
-			d := time.Duration(deviceCode.Interval) * time.Second
-			log.Debug().Msgf("Waiting %d seconds before trying again.", deviceCode.Interval)
-			time.Sleep(d)
+	log.Info().Msgf("Please visit the following URL to authorize this application: https://%v", deviceCode.VerificationURIComplete)
 
-		}
-	} else {
-		log.Debug().Msg("Tidal access token found")
-		_, err := s.checkSession(s.Config.Get().Tidal.AccessToken)
+	// start poll for authorization
+	for {
+		loginResponse, err := s.tokenLogin(ctx, *deviceCode)
 		if err != nil {
-			// failed probably need to refresh
-			log.Debug().Msg("Tidal access token expired")
-			refresh, err := s.refreshSession(s.Config.Get().Tidal.RefreshToken)
-			if err != nil {
-				return err
+			// continue polling
+			log.Debug().Err(err).Msg("Failed to login with Tidal")
+		} else if loginResponse.AuthLogin.AccessToken != "" {
+			c := s.Config.Get()
+			c.Tidal.UserID = strconv.FormatInt(loginResponse.AuthLogin.User.UserID, 10)
+			c.Tidal.AccessToken = loginResponse.AuthLogin.AccessToken
+			c.Tidal.RefreshToken = loginResponse.AuthLogin.RefreshToken
+			c.Tidal.Expiry = expiryIn(loginResponse.AuthLogin.ExpiresIn)
+			c.Tidal.Scope = loginResponse.AuthLogin.Scope
+			if err := s.Config.Update(c); err != nil {
+				return fmt.Errorf("error updating Tidal config: %w", err)
 			}
 
-			s.Config.JsonConfig.Tidal.AccessToken = refresh.AccessToken
-			s.Config.Save()
+			log.Info().Msgf("Tidal - logged in as: %s", c.Tidal.UserID)
+			return nil
+		} else if loginResponse.AuthError.Error == "expired_token" {
+			return fmt.Errorf("tidal auth failed - device code expired")
+		}
 
+		d := time.Duration(deviceCode.Interval) * time.Second
+		log.Debug().Msgf("Waiting %d seconds before trying again.", deviceCode.Interval)
+		if err := sleepContext(ctx, d); err != nil {
+			return err
 		}
+	}
+}
 
-		log.Debug().Msg("Tidal access token valid")
+// AuthStatus describes the Tidal account that is logged in.
+type AuthStatus struct {
+	UserID      string
+	CountryCode string
+	Expiry      time.Time // zero if unknown
+	Scopes      []string
+}
+
+// Status checks the saved tokens without prompting to log in, refreshing the access token if it expired.
+func (s *Service) Status(ctx context.Context) (*AuthStatus, error) {
+	if err := s.DeviceAuthenticate(ctx, false); err != nil {
+		return nil, err
 	}
 
-	s.AccessToken = s.Config.Get().Tidal.AccessToken
-	s.UserID = s.Config.Get().Tidal.UserID
+	token, err := s.userToken.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	return nil
+	session, err := s.checkSession(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	c := s.Config.Get()
+
+	return &AuthStatus{
+		UserID:      strconv.FormatInt(session.UserID, 10),
+		CountryCode: session.CountryCode,
+		Expiry:      c.Tidal.Expiry,
+		Scopes:      strings.Fields(c.Tidal.Scope),
+	}, nil
 }
 
-func (s *Service) GetTrackByISRCv2(ctx context.Context, isrc string) (*tidal_tracks.TracksResource, error) {
+// Logout removes the saved Tidal tokens.
+func Logout(jsonConfig *config.JsonConfigService) error {
+	c := jsonConfig.Get()
+	c.Tidal = config.TidalConfig{}
+	return jsonConfig.Update(c)
+}
 
-	resp, err := s.TracksApiClient.GetTracksWithResponse(ctx, &tidal_tracks.GetTracksParams{CountryCode: "US", FilterIsrc: &[]string{isrc}})
+func (s *Service) GetTrackByISRCv2(ctx context.Context, isrc string, country string) (*tidal_tracks.TracksResource, error) {
+
+	resp, err := s.TracksApiClient.GetTracksWithResponse(ctx, &tidal_tracks.GetTracksParams{CountryCode: country, FilterIsrc: &[]string{isrc}})
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode() != http.StatusOK {
+	if resp.StatusCode() != http.StatusOK || resp.ApplicationvndApiJSON200 == nil {
 		log.Error().Str("response", string(resp.Body)).Msg("failed to get tracks")
-		return nil, fmt.Errorf("failed to get tracks: %s", resp.Status())
+		return nil, newAPIError("get tracks", resp.HTTPResponse, resp.Body)
 	}
 
 	tracks := *resp.ApplicationvndApiJSON200
 
-	if *tracks.Data == nil || len(*tracks.Data) == 0 {
-		return nil, fmt.Errorf("track not found")
+	if tracks.Data == nil || len(*tracks.Data) == 0 {
+		return nil, fmt.Errorf("track with isrc %s: %w", isrc, ErrNotFound)
 	}
 
 	return &(*tracks.Data)[0], nil
 }
 
-func (s *Service) SearchTrackv2(ctx context.Context, query string, country string) (*[]tidal_tracks.TracksResource, error) {
-	resp, err := s.SearchApiClient.GetSearchResultsTracksRelationshipWithResponse(ctx, query, &tidal_search.GetSearchResultsTracksRelationshipParams{CountryCode: country, Include: &[]string{"tracks"}})
+// GetArtistIDsByISRCv2 returns the IDs of the artists of every track with the ISRC.
+// An empty slice is returned if no track has the ISRC.
+func (s *Service) GetArtistIDsByISRCv2(ctx context.Context, isrc string, country string) ([]string, error) {
+	resp, err := s.TracksApiClient.GetTracksWithResponse(ctx, &tidal_tracks.GetTracksParams{CountryCode: country, FilterIsrc: &[]string{isrc}, Include: &[]string{"artists"}})
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("failed to search tracks: %s", resp.Status())
+	if resp.StatusCode() != http.StatusOK || resp.ApplicationvndApiJSON200 == nil {
+		return nil, newAPIError("get tracks", resp.HTTPResponse, resp.Body)
 	}
 
 	tracks := *resp.ApplicationvndApiJSON200
+	if tracks.Data == nil {
+		return []string{}, nil
+	}
+
+	artistIds := []string{}
+	for _, track := range *tracks.Data {
+		if track.Relationships == nil || track.Relationships.Artists.Data == nil {
+			continue
+		}
+		for _, artist := range *track.Relationships.Artists.Data {
+			artistIds = append(artistIds, artist.Id)
+		}
+	}
+
+	return artistIds, nil
+}
+
+// TrackCandidate is a Tidal track along with the names of its artists and album.
+type TrackCandidate struct {
+	Track   tidal_tracks.TracksResource
+	Artists []string
+	Album   string
+}
+
+// maximum number of search results fetched as match candidates
+const maxSearchCandidates = 10
+
+// SearchTrackCandidatesv2 searches for tracks and returns the results with their artists and album.
+// An empty slice is returned if the search has no results.
+func (s *Service) SearchTrackCandidatesv2(ctx context.Context, query string, country string) ([]TrackCandidate, error) {
+	resp, err := s.SearchApiClient.GetSearchResultsTracksRelationshipWithResponse(ctx, query, &tidal_search.GetSearchResultsTracksRelationshipParams{CountryCode: country})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() != http.StatusOK || resp.ApplicationvndApiJSON200 == nil {
+		return nil, newAPIError("search tracks", resp.HTTPResponse, resp.Body)
+	}
+
+	searchResults := *resp.ApplicationvndApiJSON200
+	if searchResults.Data == nil || len(*searchResults.Data) == 0 {
+		return []TrackCandidate{}, nil
+	}
+
+	var trackIds []string
+	for _, resourceIdentifier := range *searchResults.Data {
+		if len(trackIds) == maxSearchCandidates {
+			break
+		}
+		trackIds = append(trackIds, resourceIdentifier.Id)
+	}
+
+	// fetch all tracks in a single request including their artists and albums
+	trackResp, err := s.TracksApiClient.GetTracksWithResponse(ctx, &tidal_tracks.GetTracksParams{CountryCode: country, FilterId: &trackIds, Include: &[]string{"artists", "albums"}})
+	if err != nil {
+		return nil, err
+	}
+	if trackResp.StatusCode() != http.StatusOK || trackResp.ApplicationvndApiJSON200 == nil {
+		return nil, newAPIError("get tracks", trackResp.HTTPResponse, trackResp.Body)
+	}
 
-	if *tracks.Data == nil || len(*tracks.Data) == 0 {
-		return nil, fmt.Errorf("track not found")
+	respData := *trackResp.ApplicationvndApiJSON200
+	if respData.Data == nil {
+		return []TrackCandidate{}, nil
 	}
 
-	var responseTracks []tidal_tracks.TracksResource
+	artistNames, albumTitles, err := includedNames(respData.Included)
+	if err != nil {
+		return nil, err
+	}
 
-	max := 5
-	if len(*tracks.Data) < max {
-		max = len(*tracks.Data)
+	tracks := make(map[string]tidal_tracks.TracksResource)
+	for _, track := range *respData.Data {
+		tracks[track.Id] = track
 	}
 
-	for i := 0; i < max; i++ {
-		trackId := (*tracks.Data)[i].Id
-		// Get track
-		trackResp, err := s.TracksApiClient.GetTracksWithResponse(ctx, &tidal_tracks.GetTracksParams{CountryCode: "US", FilterId: &[]string{trackId}})
+	// keep the order of the search results
+	candidates := make([]TrackCandidate, 0, len(trackIds))
+	for _, trackId := range trackIds {
+		track, ok := tracks[trackId]
+		if !ok {
+			log.Warn().Str("track_id", trackId).Msg("track not found")
+			continue
+		}
+
+		candidate := TrackCandidate{Track: track}
+		if track.Relationships != nil {
+			if track.Relationships.Artists.Data != nil {
+				for _, artist := range *track.Relationships.Artists.Data {
+					if name, ok := artistNames[artist.Id]; ok {
+						candidate.Artists = append(candidate.Artists, name)
+					}
+				}
+			}
+			if track.Relationships.Albums.Data != nil && len(*track.Relationships.Albums.Data) > 0 {
+				candidate.Album = albumTitles[(*track.Relationships.Albums.Data)[0].Id]
+			}
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, nil
+}
+
+// includedResource is a resource included in a response, e.g. the artists of the requested tracks.
+type includedResource interface {
+	MarshalJSON() ([]byte, error)
+	AsArtistsResource() (tidal_tracks.ArtistsResource, error)
+	AsAlbumsResource() (tidal_tracks.AlbumsResource, error)
+}
+
+// includedNames returns the names of the included artists and the titles of the included albums by ID.
+func includedNames[T includedResource](included *[]T) (map[string]string, map[string]string, error) {
+	artistNames := make(map[string]string)
+	albumTitles := make(map[string]string)
+	if included == nil {
+		return artistNames, albumTitles, nil
+	}
+
+	for _, resource := range *included {
+		var resourceIdentifier tidal_tracks.ResourceIdentifier
+		b, err := resource.MarshalJSON()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		if resp.StatusCode() != http.StatusOK {
-			return nil, fmt.Errorf("failed to search tracks: %s", resp.Status())
+		if err := json.Unmarshal(b, &resourceIdentifier); err != nil {
+			return nil, nil, err
 		}
 
-		respData := *trackResp.ApplicationvndApiJSON200
-		if *respData.Data == nil || len(*respData.Data) == 0 {
-			log.Warn().Str("track_id", trackId).Msg("track not found")
+		switch resourceIdentifier.Type {
+		case "artists":
+			artist, err := resource.AsArtistsResource()
+			if err != nil {
+				return nil, nil, err
+			}
+			if artist.Attributes != nil {
+				artistNames[artist.Id] = artist.Attributes.Name
+			}
+		case "albums":
+			album, err := resource.AsAlbumsResource()
+			if err != nil {
+				return nil, nil, err
+			}
+			if album.Attributes != nil {
+				albumTitles[album.Id] = album.Attributes.Title
+			}
+		}
+	}
+
+	return artistNames, albumTitles, nil
+}
+
+// AlbumCandidate is a Tidal album along with the names of its artists.
+type AlbumCandidate struct {
+	Album   tidal_tracks.AlbumsResource
+	Artists []string
+}
+
+// GetAlbumsByBarcodev2 returns the albums with any of the barcodes, e.g. a UPC or EAN.
+func (s *Service) GetAlbumsByBarcodev2(ctx context.Context, barcodes []string, country string) ([]AlbumCandidate, error) {
+	return s.getAlbumCandidates(ctx, &tidal_tracks.GetAlbumsParams{CountryCode: country, FilterBarcodeId: &barcodes, Include: &[]string{"artists"}}, nil)
+}
+
+// SearchAlbumCandidatesv2 searches for albums and returns the results with their artists.
+// An empty slice is returned if the search has no results.
+func (s *Service) SearchAlbumCandidatesv2(ctx context.Context, query string, country string) ([]AlbumCandidate, error) {
+	resp, err := s.SearchApiClient.GetSearchResultsAlbumsRelationshipWithResponse(ctx, query, &tidal_search.GetSearchResultsAlbumsRelationshipParams{CountryCode: country})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() != http.StatusOK || resp.ApplicationvndApiJSON200 == nil {
+		return nil, newAPIError("search albums", resp.HTTPResponse, resp.Body)
+	}
+
+	searchResults := *resp.ApplicationvndApiJSON200
+	if searchResults.Data == nil || len(*searchResults.Data) == 0 {
+		return []AlbumCandidate{}, nil
+	}
+
+	var albumIds []string
+	for _, resourceIdentifier := range *searchResults.Data {
+		if len(albumIds) == maxSearchCandidates {
+			break
+		}
+		albumIds = append(albumIds, resourceIdentifier.Id)
+	}
+
+	return s.getAlbumCandidates(ctx, &tidal_tracks.GetAlbumsParams{CountryCode: country, FilterId: &albumIds, Include: &[]string{"artists"}}, albumIds)
+}
+
+// getAlbumCandidates fetches albums including their artists.
+// If order is provided the albums are returned in that order, otherwise in the order of the response.
+func (s *Service) getAlbumCandidates(ctx context.Context, params *tidal_tracks.GetAlbumsParams, order []string) ([]AlbumCandidate, error) {
+	albumResp, err := s.TracksApiClient.GetAlbumsWithResponse(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if albumResp.StatusCode() != http.StatusOK || albumResp.ApplicationvndApiJSON200 == nil {
+		return nil, newAPIError("get albums", albumResp.HTTPResponse, albumResp.Body)
+	}
+
+	respData := *albumResp.ApplicationvndApiJSON200
+	if respData.Data == nil {
+		return []AlbumCandidate{}, nil
+	}
+
+	artistNames, _, err := includedNames(respData.Included)
+	if err != nil {
+		return nil, err
+	}
+
+	albums := make(map[string]tidal_tracks.AlbumsResource)
+	if order == nil {
+		for _, album := range *respData.Data {
+			order = append(order, album.Id)
+		}
+	}
+	for _, album := range *respData.Data {
+		albums[album.Id] = album
+	}
+
+	candidates := make([]AlbumCandidate, 0, len(order))
+	for _, albumId := range order {
+		album, ok := albums[albumId]
+		if !ok {
+			log.Warn().Str("album_id", albumId).Msg("album not found")
 			continue
 		}
 
-		responseTracks = append(responseTracks, (*respData.Data)[0])
+		candidate := AlbumCandidate{Album: album}
+		if album.Relationships != nil && album.Relationships.Artists.Data != nil {
+			for _, artist := range *album.Relationships.Artists.Data {
+				if name, ok := artistNames[artist.Id]; ok {
+					candidate.Artists = append(candidate.Artists, name)
+				}
+			}
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, nil
+}
+
+// SearchArtistsv2 searches for artists and returns the results in order of relevance.
+// An empty slice is returned if the search has no results.
+func (s *Service) SearchArtistsv2(ctx context.Context, query string, country string) ([]tidal_tracks.ArtistsResource, error) {
+	resp, err := s.SearchApiClient.GetSearchResultsArtistsRelationshipWithResponse(ctx, query, &tidal_search.GetSearchResultsArtistsRelationshipParams{CountryCode: country})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() != http.StatusOK || resp.ApplicationvndApiJSON200 == nil {
+		return nil, newAPIError("search artists", resp.HTTPResponse, resp.Body)
+	}
+
+	searchResults := *resp.ApplicationvndApiJSON200
+	if searchResults.Data == nil || len(*searchResults.Data) == 0 {
+		return []tidal_tracks.ArtistsResource{}, nil
+	}
+
+	var artistIds []string
+	for _, resourceIdentifier := range *searchResults.Data {
+		if len(artistIds) == maxSearchCandidates {
+			break
+		}
+		artistIds = append(artistIds, resourceIdentifier.Id)
+	}
+
+	artistResp, err := s.TracksApiClient.GetArtistsWithResponse(ctx, &tidal_tracks.GetArtistsParams{CountryCode: country, FilterId: &artistIds})
+	if err != nil {
+		return nil, err
+	}
+	if artistResp.StatusCode() != http.StatusOK || artistResp.ApplicationvndApiJSON200 == nil {
+		return nil, newAPIError("get artists", artistResp.HTTPResponse, artistResp.Body)
+	}
+
+	respData := *artistResp.ApplicationvndApiJSON200
+	if respData.Data == nil {
+		return []tidal_tracks.ArtistsResource{}, nil
+	}
+
+	artists := make(map[string]tidal_tracks.ArtistsResource)
+	for _, artist := range *respData.Data {
+		artists[artist.Id] = artist
+	}
+
+	// keep the order of the search results
+	results := make([]tidal_tracks.ArtistsResource, 0, len(artistIds))
+	for _, artistId := range artistIds {
+		if artist, ok := artists[artistId]; ok {
+			results = append(results, artist)
+		}
 	}
 
-	return &responseTracks, nil
+	return results, nil
 }
@@ -0,0 +1,172 @@
+package tidal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// tokens are refreshed this long before they expire so requests that are in flight do not fail
+const tokenExpiryMargin = time.Minute
+
+// tokenRefresher returns a new access token and when it expires, the expiry is zero if unknown.
+type tokenRefresher func(ctx context.Context) (string, time.Time, error)
+
+// tokenSource holds the access token of one set of credentials and refreshes it when it expires or is rejected by Tidal.
+// It is shared by concurrent requests, only one of them refreshes the token.
+type tokenSource struct {
+	mu      sync.Mutex
+	token   string
+	expiry  time.Time
+	refresh tokenRefresher
+}
+
+func newTokenSource(refresh tokenRefresher) *tokenSource {
+	return &tokenSource{refresh: refresh}
+}
+
+// set replaces the token, e.g. after the device authentication flow.
+func (t *tokenSource) set(token string, expiry time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.token = token
+	t.expiry = expiry
+}
+
+// Token returns the current token, refreshing it first if it is missing or about to expire.
+func (t *tokenSource) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && (t.expiry.IsZero() || time.Until(t.expiry) > tokenExpiryMargin) {
+		return t.token, nil
+	}
+
+	return t.refreshLocked(ctx)
+}
+
+// refreshRejected refreshes the token after Tidal rejected it.
+// If another request already refreshed the rejected token the new token is returned instead.
+func (t *tokenSource) refreshRejected(ctx context.Context, rejected string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != rejected && t.token != "" {
+		return t.token, nil
+	}
+
+	return t.refreshLocked(ctx)
+}
+
+func (t *tokenSource) refreshLocked(ctx context.Context) (string, error) {
+	token, expiry, err := t.refresh(ctx)
+	if err != nil {
+		return "", err
+	}
+	t.token = token
+	t.expiry = expiry
+	return token, nil
+}
+
+// authTransport sets the bearer token of each request.
+// When Tidal responds with 401 the token is refreshed and the request is retried once.
+type authTransport struct {
+	tokens *tokenSource
+	base   http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	token, err := t.tokens.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(withBearerToken(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// the body was consumed by the first attempt and can only be retried if it can be recreated
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	log.Debug().Str("host", req.URL.Host).Msg("Tidal access token rejected, refreshing")
+	token, err = t.tokens.refreshRejected(ctx, token)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to refresh Tidal access token")
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	retry := withBearerToken(req, token)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+
+	return t.base.RoundTrip(retry)
+}
+
+// withBearerToken returns a copy of the request with the token as its Authorization header.
+func withBearerToken(req *http.Request, token string) *http.Request {
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+// newAuthClient returns a client that authenticates every request with the token source.
+func newAuthClient(base *http.Client, tokens *tokenSource) *http.Client {
+	return &http.Client{Transport: &authTransport{tokens: tokens, base: base.Transport}}
+}
+
+// expiryIn returns the expiry of a token that is valid for the number of seconds, zero if unknown.
+func expiryIn(seconds int64) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(seconds) * time.Second)
+}
+
+// refreshUserToken refreshes the device-flow access token and saves it to the config.
+func (s *Service) refreshUserToken(ctx context.Context) (string, time.Time, error) {
+	refreshToken := s.Config.Get().Tidal.RefreshToken
+	if refreshToken == "" {
+		return "", time.Time{}, fmt.Errorf("no Tidal refresh token, log in with `auth login tidal`: %w", ErrLoginRequired)
+	}
+
+	refresh, err := s.refreshSession(ctx, refreshToken)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiry := expiryIn(refresh.ExpiresIn)
+
+	c := s.Config.Get()
+	c.Tidal.AccessToken = refresh.AccessToken
+	c.Tidal.Expiry = expiry
+	if refresh.Scope != "" {
+		c.Tidal.Scope = refresh.Scope
+	}
+	if err := s.Config.Update(c); err != nil {
+		return "", time.Time{}, fmt.Errorf("error updating Tidal config: %w", err)
+	}
+
+	log.Info().Msg("refreshed Tidal access token")
+
+	return refresh.AccessToken, expiry, nil
+}
+
+// refreshClientToken requests a new client-credentials token for the OpenAPI clients.
+func (s *Service) refreshClientToken(ctx context.Context) (string, time.Time, error) {
+	return s.clientAuth(ctx, s.ClientId, s.ClientSecret)
+}
@@ -1,17 +1,24 @@
 package tidal
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/rs/zerolog/log"
 )
 
-const countryCode = "US"
+const (
+	// maximum number of items added to a playlist or favorites in a single request
+	AddTracksChunkSize = 50
+	// maximum number of attempts when the playlist ETag changes during a request
+	maxEtagAttempts = 3
+)
 
 type CreatedPlaylist struct {
 	Trn            string      `json:"trn"`
@@ -138,31 +145,64 @@ type UserPlaylists struct {
 	Items              []Playlist `json:"items"`
 }
 
+type FavoriteTracks struct {
+	Limit              int64           `json:"limit"`
+	Offset             int64           `json:"offset"`
+	TotalNumberOfItems int64           `json:"totalNumberOfItems"`
+	Items              []FavoriteTrack `json:"items"`
+}
+
+type FavoriteTrack struct {
+	Created string `json:"created"`
+	Item    Track  `json:"item"`
+}
+
+type FavoriteAlbums struct {
+	Limit              int64           `json:"limit"`
+	Offset             int64           `json:"offset"`
+	TotalNumberOfItems int64           `json:"totalNumberOfItems"`
+	Items              []FavoriteAlbum `json:"items"`
+}
+
+type FavoriteAlbum struct {
+	Created string `json:"created"`
+	Item    Album  `json:"item"`
+}
+
+type FavoriteArtists struct {
+	Limit              int64            `json:"limit"`
+	Offset             int64            `json:"offset"`
+	TotalNumberOfItems int64            `json:"totalNumberOfItems"`
+	Items              []FavoriteArtist `json:"items"`
+}
+
+type FavoriteArtist struct {
+	Created string `json:"created"`
+	Item    Artist `json:"item"`
+}
+
 type TopHit struct {
 	Value Track  `json:"value"`
 	Type  string `json:"type"`
 }
 
-func (s *Service) standardHttpGetRequest(reqUrl string) ([]byte, error) {
-	client := &http.Client{}
-
-	req, err := http.NewRequest("GET", reqUrl, nil)
+func (s *Service) standardHttpGetRequest(ctx context.Context, reqUrl string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", reqUrl, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	// Set Headers
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.AccessToken))
 
 	// Set Query Params
 	q := url.Values{}
-	q.Add("countryCode", countryCode)
+	q.Add("countryCode", s.CountryCode)
 	q.Add("limit", "10000")
 
 	req.URL.RawQuery = q.Encode()
 
-	resp, err := client.Do(req)
+	resp, err := s.userClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -175,14 +215,14 @@ func (s *Service) standardHttpGetRequest(reqUrl string) ([]byte, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%s", string(body))
+		return nil, newAPIError(fmt.Sprintf("get %s", req.URL.Path), resp, body)
 	}
 
 	return body, nil
 }
 
-func (s *Service) GetUserPlaylists() (UserPlaylists, error) {
-	playlists, err := s.standardHttpGetRequest(fmt.Sprintf("%s/users/%s/playlists", apiURL, s.UserID))
+func (s *Service) GetUserPlaylists(ctx context.Context) (UserPlaylists, error) {
+	playlists, err := s.standardHttpGetRequest(ctx, fmt.Sprintf("%s/users/%s/playlists", apiURL, s.UserID))
 	if err != nil {
 		return UserPlaylists{}, err
 	}
@@ -196,8 +236,8 @@ func (s *Service) GetUserPlaylists() (UserPlaylists, error) {
 	return tidalUserPlaylists, nil
 }
 
-func (s *Service) GetPlaylist(playlistID string) (*Playlist, error) {
-	playlist, err := s.standardHttpGetRequest(fmt.Sprintf("%s/playlists/%s", apiURL, playlistID))
+func (s *Service) GetPlaylist(ctx context.Context, playlistID string) (*Playlist, error) {
+	playlist, err := s.standardHttpGetRequest(ctx, fmt.Sprintf("%s/playlists/%s", apiURL, playlistID))
 	if err != nil {
 		return nil, err
 	}
@@ -211,16 +251,13 @@ func (s *Service) GetPlaylist(playlistID string) (*Playlist, error) {
 	return &tidalPlaylist, nil
 }
 
-func (s *Service) CreatePlaylist(name, description string) (*Playlist, error) {
-	client := &http.Client{}
-
-	req, err := http.NewRequest("PUT", fmt.Sprintf("%s/my-collection/playlists/folders/create-playlist", apiURL2), nil)
+func (s *Service) CreatePlaylist(ctx context.Context, name, description string) (*Playlist, error) {
+	req, err := http.NewRequestWithContext(ctx, "PUT", fmt.Sprintf("%s/my-collection/playlists/folders/create-playlist", apiURL2), nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.AccessToken)
 
 	params := url.Values{}
 	params.Set("folderId", "root")
@@ -229,7 +266,7 @@ func (s *Service) CreatePlaylist(name, description string) (*Playlist, error) {
 
 	req.URL.RawQuery = params.Encode()
 
-	resp, err := client.Do(req)
+	resp, err := s.userClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -237,7 +274,7 @@ func (s *Service) CreatePlaylist(name, description string) (*Playlist, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("failed to create playlist: %s", resp.Status)
+		return nil, newAPIError("create playlist", resp, nil)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -254,26 +291,23 @@ func (s *Service) CreatePlaylist(name, description string) (*Playlist, error) {
 	return &createdPlaylist.Data, nil
 }
 
-func (s *Service) UpdatePlaylist(playlistID, name, description string) error {
+func (s *Service) UpdatePlaylist(ctx context.Context, playlistID, name, description string) error {
 	// updated name and description sent in body no params
-	client := &http.Client{}
-
 	data := url.Values{}
 	data.Set("title", name)
 	data.Set("description", description)
 
 	encodedData := data.Encode()
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/playlists/%s", apiURL, playlistID), strings.NewReader(encodedData))
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/playlists/%s", apiURL, playlistID), strings.NewReader(encodedData))
 	if err != nil {
 		return err
 	}
 
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.AccessToken)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := client.Do(req)
+	resp, err := s.userClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -281,31 +315,28 @@ func (s *Service) UpdatePlaylist(playlistID, name, description string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("failed to update playlist: %s", resp.Status)
+		return newAPIError("update playlist", resp, nil)
 	}
 
 	return nil
 }
 
-func (s *Service) getPlaylistEtag(id string) (string, error) {
-	client := &http.Client{}
-
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/playlists/%s", apiURL, id), nil)
+func (s *Service) getPlaylistEtag(ctx context.Context, id string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/playlists/%s", apiURL, id), nil)
 	if err != nil {
 		return "", err
 	}
 
 	// Set Headers
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.AccessToken))
 
 	// Set Query Params
 	q := url.Values{}
-	q.Add("countryCode", countryCode)
+	q.Add("countryCode", s.CountryCode)
 
 	req.URL.RawQuery = q.Encode()
 
-	resp, err := client.Do(req)
+	resp, err := s.userClient.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -318,7 +349,7 @@ func (s *Service) getPlaylistEtag(id string) (string, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("%s", string(body))
+		return "", newAPIError("get playlist etag", resp, body)
 	}
 
 	playlistEtag := resp.Header.Get("ETag")
@@ -326,38 +357,99 @@ func (s *Service) getPlaylistEtag(id string) (string, error) {
 	return playlistEtag, nil
 }
 
-func (s *Service) AddTrackToPlaylist(playlistId string, trackId string) error {
-	playlistEtag, err := s.getPlaylistEtag(playlistId)
+func (s *Service) GetPlaylistTracks(ctx context.Context, id string) (*TidalPlaylistTracks, error) {
+	body, err := s.standardHttpGetRequest(ctx, fmt.Sprintf("%s/playlists/%s/tracks", apiURL, id))
+	if err != nil {
+		return nil, err
+	}
+
+	var tidalPlaylistTracks TidalPlaylistTracks
+	err = json.Unmarshal(body, &tidalPlaylistTracks)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tidalPlaylistTracks, nil
+}
+
+// RemoveTracksFromPlaylist removes the items at the provided indices from the playlist.
+// Indices are positions in the playlist at the time of the request.
+func (s *Service) RemoveTracksFromPlaylist(ctx context.Context, playlistId string, indices []int64) error {
+	if len(indices) == 0 {
+		return nil
+	}
+
+	playlistEtag, err := s.getPlaylistEtag(ctx, playlistId)
+	if err != nil {
+		return err
+	}
+
+	indexStrings := make([]string, 0, len(indices))
+	for _, index := range indices {
+		indexStrings = append(indexStrings, strconv.FormatInt(index, 10))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", fmt.Sprintf("%s/playlists/%s/items/%s", apiURL, playlistId, strings.Join(indexStrings, ",")), nil)
+	if err != nil {
+		return err
+	}
+
+	// Set Headers
+	req.Header.Set("If-None-Match", playlistEtag)
+
+	// Set Query Params
+	q := url.Values{}
+	q.Add("countryCode", s.CountryCode)
+
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := s.userClient.Do(req)
 	if err != nil {
 		return err
 	}
 
-	client := &http.Client{}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return newAPIError("remove tracks from playlist", resp, body)
+	}
+
+	return nil
+}
+
+// MoveTrackInPlaylist moves the item at the provided index to a new position in the playlist.
+func (s *Service) MoveTrackInPlaylist(ctx context.Context, playlistId string, index int64, toIndex int64) error {
+	playlistEtag, err := s.getPlaylistEtag(ctx, playlistId)
+	if err != nil {
+		return err
+	}
 
 	data := url.Values{}
-	data.Set("trackIds", fmt.Sprintf("%v", trackId))
-	data.Set("onArtifactNotFound", "FAIL")
-	data.Set("onDupes", "FAIL")
+	data.Set("toIndex", strconv.FormatInt(toIndex, 10))
 
 	encodedData := data.Encode()
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/playlists/%s/items", apiURL, playlistId), strings.NewReader(encodedData))
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/playlists/%s/items/%d", apiURL, playlistId, index), strings.NewReader(encodedData))
 	if err != nil {
 		return err
 	}
 
 	// Set Headers
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.AccessToken))
 	req.Header.Set("If-None-Match", playlistEtag)
 
 	// Set Query Params
 	q := url.Values{}
-	q.Add("countryCode", countryCode)
+	q.Add("countryCode", s.CountryCode)
 
 	req.URL.RawQuery = q.Encode()
 
-	resp, err := client.Do(req)
+	resp, err := s.userClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -369,30 +461,192 @@ func (s *Service) AddTrackToPlaylist(playlistId string, trackId string) error {
 		return err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusConflict {
-			log.Debug().Msgf("Track %v already exists in playlist %s", trackId, playlistId)
-		} else {
-			return fmt.Errorf("failed to add track to playlist: %s", string(body))
-		}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return newAPIError("move track in playlist", resp, body)
+	}
 
-		return err
+	return nil
+}
+
+// AddTracksToPlaylist adds the tracks to the playlist in chunks.
+// Tracks that already exist in the playlist are skipped.
+func (s *Service) AddTracksToPlaylist(ctx context.Context, playlistId string, trackIds []string) error {
+	for start := 0; start < len(trackIds); start += AddTracksChunkSize {
+		end := min(start+AddTracksChunkSize, len(trackIds))
+		if err := s.addTracksToPlaylist(ctx, playlistId, trackIds[start:end]); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (s *Service) GetPlaylistTracks(id string) (*TidalPlaylistTracks, error) {
-	body, err := s.standardHttpGetRequest(fmt.Sprintf("%s/playlists/%s/tracks", apiURL, id))
+// addTracksToPlaylist adds a single chunk of tracks to the playlist.
+// The playlist ETag is refetched and the request retried if the playlist was modified in between.
+func (s *Service) addTracksToPlaylist(ctx context.Context, playlistId string, trackIds []string) error {
+	for attempt := 1; ; attempt++ {
+		playlistEtag, err := s.getPlaylistEtag(ctx, playlistId)
+		if err != nil {
+			return err
+		}
+
+		data := url.Values{}
+		data.Set("trackIds", strings.Join(trackIds, ","))
+		data.Set("onArtifactNotFound", "SKIP")
+		data.Set("onDupes", "SKIP")
+
+		encodedData := data.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/playlists/%s/items", apiURL, playlistId), strings.NewReader(encodedData))
+		if err != nil {
+			return err
+		}
+
+		// Set Headers
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("If-None-Match", playlistEtag)
+
+		// Set Query Params
+		q := url.Values{}
+		q.Add("countryCode", s.CountryCode)
+
+		req.URL.RawQuery = q.Encode()
+
+		resp, err := s.userClient.Do(req)
+		if err != nil {
+			return err
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusPreconditionFailed && attempt < maxEtagAttempts {
+			log.Debug().Str("playlist_id", playlistId).Int("attempt", attempt).Msg("playlist etag changed, retrying")
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return newAPIError("add tracks to playlist", resp, body)
+		}
+
+		return nil
+	}
+}
+
+// GetFavoriteTracks returns the tracks in the user's favorite tracks collection.
+func (s *Service) GetFavoriteTracks(ctx context.Context) (*FavoriteTracks, error) {
+	body, err := s.standardHttpGetRequest(ctx, fmt.Sprintf("%s/users/%s/favorites/tracks", apiURL, s.UserID))
 	if err != nil {
 		return nil, err
 	}
 
-	var tidalPlaylistTracks TidalPlaylistTracks
-	err = json.Unmarshal(body, &tidalPlaylistTracks)
+	var favoriteTracks FavoriteTracks
+	err = json.Unmarshal(body, &favoriteTracks)
 	if err != nil {
 		return nil, err
 	}
 
-	return &tidalPlaylistTracks, nil
+	return &favoriteTracks, nil
+}
+
+// AddFavoriteTracks adds the tracks to the user's favorite tracks collection in chunks.
+func (s *Service) AddFavoriteTracks(ctx context.Context, trackIds []string) error {
+	return s.addFavorites(ctx, "tracks", "trackIds", trackIds)
+}
+
+// GetFavoriteAlbums returns the albums in the user's favorite albums collection.
+func (s *Service) GetFavoriteAlbums(ctx context.Context) (*FavoriteAlbums, error) {
+	body, err := s.standardHttpGetRequest(ctx, fmt.Sprintf("%s/users/%s/favorites/albums", apiURL, s.UserID))
+	if err != nil {
+		return nil, err
+	}
+
+	var favoriteAlbums FavoriteAlbums
+	err = json.Unmarshal(body, &favoriteAlbums)
+	if err != nil {
+		return nil, err
+	}
+
+	return &favoriteAlbums, nil
+}
+
+// AddFavoriteAlbums adds the albums to the user's favorite albums collection in chunks.
+func (s *Service) AddFavoriteAlbums(ctx context.Context, albumIds []string) error {
+	return s.addFavorites(ctx, "albums", "albumIds", albumIds)
+}
+
+// GetFavoriteArtists returns the artists in the user's favorite artists collection.
+func (s *Service) GetFavoriteArtists(ctx context.Context) (*FavoriteArtists, error) {
+	body, err := s.standardHttpGetRequest(ctx, fmt.Sprintf("%s/users/%s/favorites/artists", apiURL, s.UserID))
+	if err != nil {
+		return nil, err
+	}
+
+	var favoriteArtists FavoriteArtists
+	err = json.Unmarshal(body, &favoriteArtists)
+	if err != nil {
+		return nil, err
+	}
+
+	return &favoriteArtists, nil
+}
+
+// AddFavoriteArtists adds the artists to the user's favorite artists collection in chunks.
+func (s *Service) AddFavoriteArtists(ctx context.Context, artistIds []string) error {
+	return s.addFavorites(ctx, "artists", "artistIds", artistIds)
+}
+
+// addFavorites adds the items to the user's favorites collection of the provided kind, e.g. "tracks".
+func (s *Service) addFavorites(ctx context.Context, kind string, idsParam string, ids []string) error {
+	for start := 0; start < len(ids); start += AddTracksChunkSize {
+		end := min(start+AddTracksChunkSize, len(ids))
+		if err := s.addFavoritesChunk(ctx, kind, idsParam, ids[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) addFavoritesChunk(ctx context.Context, kind string, idsParam string, ids []string) error {
+	data := url.Values{}
+	data.Set(idsParam, strings.Join(ids, ","))
+	data.Set("onArtifactNotFound", "SKIP")
+
+	encodedData := data.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/users/%s/favorites/%s", apiURL, s.UserID, kind), strings.NewReader(encodedData))
+	if err != nil {
+		return err
+	}
+
+	// Set Headers
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// Set Query Params
+	q := url.Values{}
+	q.Add("countryCode", s.CountryCode)
+
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := s.userClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return newAPIError(fmt.Sprintf("add favorite %s", kind), resp, body)
+	}
+
+	return nil
 }
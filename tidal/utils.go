@@ -23,6 +23,25 @@ func WriteTidalPlaylist(filename string, playlist *Playlist, config config.Confi
 	return os.WriteFile(fmt.Sprintf(config.DataPath+"/tidal/%s.json", filename), json, 0644)
 }
 
+type MissingTracks struct {
+	Playlist Playlist `json:"playlist"`
+	Tracks   []Track  `json:"tracks"`
+	// Reasons is why each track was not found, keyed by Tidal track ID
+	Reasons map[string]string `json:"reasons,omitempty"`
+}
+
+// WriteMissingTracks writes Tidal playlist tracks that were not found on Spotify to disk
+func WriteMissingTracks(filename string, missingTracks MissingTracks, config config.Config) error {
+	if err := os.MkdirAll(config.DataPath+"/missing", 0755); err != nil {
+		return err
+	}
+	json, err := json.Marshal(missingTracks)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fmt.Sprintf(config.DataPath+"/missing/%s.json", filename), json, 0644)
+}
+
 // parseISODuration converts an ISO 8601 duration (e.g., "P30M5S") to time.Duration
 func ParseISODuration(isoDuration string) (time.Duration, error) {
 	re := regexp.MustCompile(`P(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?`)